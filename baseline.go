@@ -0,0 +1,304 @@
+package migrator
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+func (m *migrator) Baseline(version int64) error {
+	return m.BaselineContext(context.Background(), version)
+}
+
+func (m *migrator) BaselineContext(ctx context.Context, version int64) error {
+	if version < 0 || version > m.lastVersion {
+		return InvalidTargetVersionError{Version: version}
+	}
+
+	return m.withLock(ctx, func() error {
+		migrations := m.unrecorded(version)
+		if len(migrations) == 0 {
+			m.logger.Info("nothing to baseline", "version", m.currentVersion)
+			return nil
+		}
+
+		if m.dryRun {
+			for _, migration := range migrations {
+				m.logDryRun("dry run: would record migration", migration, nil)
+			}
+
+			return nil
+		}
+
+		tx, err := m.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for baseline: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		for _, migration := range migrations {
+			err = m.recordMigration(ctx, tx, migration)
+			if err != nil {
+				return wrapInterrupted(ctx, migration.version, err)
+			}
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return fmt.Errorf("failed to commit baseline: %w", err)
+		}
+
+		for _, migration := range migrations {
+			m.markApplied(migration.version)
+		}
+
+		m.logger.Info("migrations recorded as applied", "version", version)
+		return nil
+	})
+}
+
+// partitionBaselines separates the baseline migrations from the other ones.
+//
+// Squash migrations are in both lists.
+func partitionBaselines(migrations []Migration) ([]Migration, []Migration) {
+	var regular, baselines []Migration
+	for _, migration := range migrations {
+		switch {
+		case migration.baseline:
+			baselines = append(baselines, migration)
+		case migration.squash:
+			regular = append(regular, migration)
+			baselines = append(baselines, migration)
+		default:
+			regular = append(regular, migration)
+		}
+	}
+
+	return regular, baselines
+}
+
+// validateBaselines checks that every baseline has a unique version matching a migration, and
+// sorts them.
+func validateBaselines(baselines []Migration, migrations []Migration) error {
+	seenVersions := make(map[int64]Migration)
+	for _, baseline := range baselines {
+		if seen, ok := seenVersions[baseline.version]; ok {
+			return newDuplicateMigrationVersionError(seen, baseline)
+		}
+
+		seenVersions[baseline.version] = baseline
+
+		if !slices.ContainsFunc(
+			migrations, func(m Migration) bool { return m.version == baseline.version },
+		) {
+			return MissingMigrationVersionError{Version: baseline.version}
+		}
+	}
+
+	slices.SortFunc(
+		baselines,
+		func(a Migration, b Migration) int { return cmp.Compare(a.version, b.version) },
+	)
+
+	return nil
+}
+
+// baselineFor returns the latest baseline up to the given version, if the database is empty.
+func (m *migrator) baselineFor(version int64) (Migration, bool) {
+	empty := m.currentVersion == 0
+	if m.sparse {
+		empty = len(m.applied) == 0
+	}
+
+	if !empty {
+		return Migration{}, false
+	}
+
+	for _, baseline := range slices.Backward(m.baselines) {
+		if baseline.version <= version {
+			return baseline, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+// above returns the migrations with a version greater than the given one.
+func above(migrations []Migration, version int64) []Migration {
+	var result []Migration
+	for _, migration := range migrations {
+		if migration.version > version {
+			result = append(result, migration)
+		}
+	}
+
+	return result
+}
+
+// migrateBaseline applies a baseline and records the migrations up to its version.
+//
+// It returns false if the database was not empty anymore, in which case nothing is done.
+func (m *migrator) migrateBaseline(ctx context.Context, baseline Migration) (bool, error) {
+	if m.dryRun {
+		m.logDryRun("dry run: would apply baseline", baseline, baseline.upSQL)
+		return true, nil
+	}
+
+	start := time.Now()
+
+	applied, err := m.applyBaseline(ctx, baseline)
+	if err != nil {
+		m.logger.Error(
+			"failed to apply baseline",
+			"version", baseline.version,
+			"name", baseline.name,
+			"duration", time.Since(start),
+			"error", err,
+		)
+
+		m.callErrorHooks(ctx, baseline.info(false), err)
+		return false, wrapInterrupted(ctx, baseline.version, err)
+	}
+
+	if !applied {
+		m.logger.Info(
+			"database is not empty, skipping baseline",
+			"version", baseline.version,
+			"name", baseline.name,
+		)
+		return false, nil
+	}
+
+	for _, migration := range m.migrations {
+		if migration.version <= baseline.version {
+			m.markApplied(migration.version)
+		}
+	}
+
+	m.logger.Info(
+		"baseline applied",
+		"version", baseline.version,
+		"name", baseline.name,
+		"duration", time.Since(start),
+	)
+	return true, nil
+}
+
+// applyBaseline executes a baseline and records the migrations up to its version, in a
+// transaction unless the baseline disables it.
+//
+// It returns false if the database is not empty.
+func (m *migrator) applyBaseline(ctx context.Context, baseline Migration) (bool, error) {
+	m.logger.Info("applying baseline", "version", baseline.version, "name", baseline.name)
+
+	if baseline.noTransaction {
+		conn, err := m.db.Conn(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get a connection for baseline: %w", err)
+		}
+		defer func() { _ = conn.Close() }()
+
+		empty, err := m.isEmpty(ctx, conn)
+		if err != nil || !empty {
+			return false, err
+		}
+
+		err = m.beforeMigration(ctx, nil, baseline.info(false))
+		if err != nil {
+			return false, err
+		}
+
+		err = execWithoutTransaction(ctx, conn, baseline.version, baseline.upSQL)
+		if err != nil {
+			return false, err
+		}
+
+		// the baseline is not recorded if the after hook fails, like if a statement failed
+		err = m.afterMigration(ctx, nil, baseline.info(false))
+		if err != nil {
+			return false, err
+		}
+
+		return true, m.recordBaseline(ctx, conn, baseline.version)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction for baseline: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	empty, err := m.isEmpty(ctx, tx)
+	if err != nil || !empty {
+		return false, err
+	}
+
+	err = m.beforeMigration(ctx, tx, baseline.info(false))
+	if err != nil {
+		return false, err
+	}
+
+	for _, sql := range baseline.upSQL {
+		_, err = tx.ExecContext(ctx, sql)
+		if err != nil {
+			return false, fmt.Errorf("failed to apply baseline %d: %w", baseline.version, err)
+		}
+	}
+
+	err = m.recordBaseline(ctx, tx, baseline.version)
+	if err != nil {
+		return false, err
+	}
+
+	err = m.afterMigration(ctx, tx, baseline.info(false))
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, fmt.Errorf("failed to commit baseline %d: %w", baseline.version, err)
+	}
+
+	return true, nil
+}
+
+// recordBaseline records every migration up to the version of a baseline.
+func (m *migrator) recordBaseline(ctx context.Context, e execer, version int64) error {
+	for _, migration := range m.unrecorded(version) {
+		err := m.recordMigration(ctx, e, migration)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unrecorded returns the migrations not applied yet up to the given version.
+//
+// With sequential versions, the versions whose file was deleted after being squashed are
+// returned too, as migrations without statements.
+func (m *migrator) unrecorded(version int64) []Migration {
+	if m.sparse {
+		return m.pending(version)
+	}
+
+	var migrations []Migration
+	for v := int64(1); v <= version; v++ {
+		if m.isAppliedVersion(v) {
+			continue
+		}
+
+		migration, ok := m.migration(v)
+		if !ok {
+			migration = Migration{version: v}
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	return migrations
+}
@@ -0,0 +1,160 @@
+package migrator_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+func TestBaseline(t *testing.T) {
+	t.Parallel()
+
+	// the schema was created by another tool
+	db, m := getDBAndMigrator(
+		t,
+		migrationsOKFS,
+		`CREATE TABLE test_table (id INTEGER PRIMARY KEY, name TEXT NOT NULL, description TEXT)`,
+	)
+	defer db.Close()
+
+	err := m.Baseline(2)
+	if err != nil {
+		t.Fatalf("failed to baseline: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 2 {
+		t.Fatalf("expected version 2, got: %d", version)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt.IsZero() {
+			t.Fatalf("expected every migration to be applied, got: %+v", statuses)
+		}
+	}
+
+	err = m.Baseline(5)
+
+	var invalidErr migrator.InvalidTargetVersionError
+	if !errors.As(err, &invalidErr) || invalidErr.Version != 5 {
+		t.Fatalf("expected InvalidTargetVersionError, got: %v", err)
+	}
+}
+
+var baselineFS = fstest.MapFS{
+	"1_a.sql": &fstest.MapFile{
+		Data: []byte("-- +migrate Up\nCREATE TABLE a (id INTEGER PRIMARY KEY);\n"),
+	},
+	"2_b.sql": &fstest.MapFile{
+		Data: []byte("-- +migrate Up\nCREATE TABLE b (id INTEGER PRIMARY KEY);\n"),
+	},
+	"2_schema.sql": &fstest.MapFile{
+		Data: []byte(`-- +migrate Up
+-- +migrate Baseline
+CREATE TABLE a (id INTEGER PRIMARY KEY, from_baseline INTEGER);
+CREATE TABLE b (id INTEGER PRIMARY KEY);
+`),
+	},
+	"3_c.sql": &fstest.MapFile{
+		Data: []byte("-- +migrate Up\nCREATE TABLE c (id INTEGER PRIMARY KEY);\n"),
+	},
+}
+
+func TestMigrate_BaselineFile(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, baselineFS)
+	defer db.Close()
+
+	plan, err := m.Plan()
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+
+	if len(plan) != 2 ||
+		plan[0].Version != 2 || plan[0].Name != "schema" || !plan[0].Baseline ||
+		plan[1].Version != 3 || plan[1].Baseline {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	_, err = db.Exec(`SELECT from_baseline FROM a`)
+	if err != nil {
+		t.Fatalf("expected the baseline to be applied, got error: %v", err)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+
+	if count != 3 {
+		t.Fatalf("expected 3 recorded migrations, got: %d", count)
+	}
+}
+
+func TestMigrate_BaselineFileNotEmpty(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, baselineFS)
+	defer db.Close()
+
+	err := m.MigrateTo(1)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	_, err = db.Exec(`SELECT from_baseline FROM a`)
+	if err == nil {
+		t.Fatalf("expected the baseline to be skipped, but it was applied")
+	}
+
+	_, err = db.Exec(`SELECT id FROM c`)
+	if err != nil {
+		t.Fatalf("expected table c to exist, got error: %v", err)
+	}
+}
+
+func TestNew_InvalidBaseline(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"1_a.sql": baselineFS["1_a.sql"],
+		"2_schema.sql": &fstest.MapFile{
+			Data: []byte("-- +migrate Up\n-- +migrate Baseline\nCREATE TABLE a (id INTEGER);\n"),
+		},
+	}
+
+	err := migrator.Validate(fsys)
+
+	var missingErr migrator.MissingMigrationVersionError
+	if !errors.As(err, &missingErr) || missingErr.Version != 2 {
+		t.Fatalf("expected MissingMigrationVersionError for version 2, got: %v", err)
+	}
+}
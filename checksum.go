@@ -0,0 +1,160 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// computeChecksum returns the SHA-256 of the statements of a migration.
+//
+// Statements are trimmed, so that changing blank lines around them does not change the
+// checksum. Comments are part of the statement following them and are not removed, so that
+// changing a comment changes the checksum.
+func computeChecksum(statements []string) string {
+	hash := sha256.New()
+	for _, statement := range statements {
+		hash.Write([]byte(strings.TrimSpace(statement)))
+		hash.Write([]byte{'\n'})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// historyRow is a row of the history table.
+type historyRow struct {
+	version   int64
+	checksum  sql.NullString
+	appliedAt sql.NullTime
+}
+
+func (m *migrator) getHistory(ctx context.Context) ([]historyRow, error) {
+	if m.historyMissing {
+		return nil, nil
+	}
+
+	rows, err := m.db.QueryContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT version, checksum, applied_at FROM %s WHERE namespace = %s ORDER BY version",
+			m.table,
+			m.dialect.Placeholder(1),
+		),
+		m.namespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var history []historyRow
+	for rows.Next() {
+		var row historyRow
+		err = rows.Scan(&row.version, &row.checksum, &row.appliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// verifyChecksums checks that the applied migrations were not modified.
+//
+// Migrations applied before checksums were recorded, Go migrations and squash migrations are
+// not checked.
+func (m *migrator) verifyChecksums(ctx context.Context) error {
+	history, err := m.getHistory(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range history {
+		migration, ok := m.migration(row.version)
+		if !ok || !row.checksum.Valid || migration.checksum == "" || migration.squash {
+			continue
+		}
+
+		if row.checksum.String != migration.checksum {
+			return ChecksumMismatchError{
+				Version:  row.version,
+				Expected: row.checksum.String,
+				Actual:   migration.checksum,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *migrator) Repair() error {
+	return m.RepairContext(context.Background())
+}
+
+func (m *migrator) RepairContext(ctx context.Context) error {
+	return m.lock(ctx, func() error {
+		err := m.setupHistoryTable(ctx)
+		if err != nil {
+			return err
+		}
+
+		history, err := m.getHistory(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range history {
+			migration, ok := m.migration(row.version)
+			if !ok || migration.checksum == "" || migration.squash ||
+				row.checksum.String == migration.checksum {
+				continue
+			}
+
+			if m.dryRun {
+				m.logger.Info(
+					"dry run: would update migration checksum",
+					"version", migration.version,
+					"name", migration.name,
+					"checksum", migration.checksum,
+				)
+				continue
+			}
+
+			m.logger.Info(
+				"updating migration checksum",
+				"version", migration.version,
+				"name", migration.name,
+			)
+
+			_, err = m.db.ExecContext(
+				ctx,
+				fmt.Sprintf(
+					"UPDATE %s SET checksum = %s WHERE namespace = %s AND version = %s",
+					m.table,
+					m.dialect.Placeholder(1),
+					m.dialect.Placeholder(2),
+					m.dialect.Placeholder(3),
+				),
+				migration.checksum,
+				m.namespace,
+				migration.version,
+			)
+			if err != nil {
+				return fmt.Errorf(
+					"failed to update checksum of migration %d: %w", migration.version, err,
+				)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,198 @@
+package migrator_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+// modifiedFS returns a copy of fsys where a file content is replaced.
+func modifiedFS(t *testing.T, fsys fs.FS, filename string, content string) fs.FS {
+	t.Helper()
+
+	modified := fstest.MapFS{}
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		modified[path] = &fstest.MapFile{Data: data}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to copy migrations: %v", err)
+	}
+
+	modified[filename] = &fstest.MapFile{Data: []byte(content)}
+	return modified
+}
+
+func TestMigrate_ChecksumMismatch(t *testing.T) {
+	// an applied migration is modified
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsOKFS)
+	defer db.Close()
+
+	err := m.MigrateTo(3)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	fsys := modifiedFS(
+		t,
+		migrationsOKFS,
+		"2_change_table.sql",
+		"-- +migrate Up\nALTER TABLE test_table ADD COLUMN comment TEXT;\n",
+	)
+
+	_, err = migrator.New(db, fsys)
+
+	var mismatchErr migrator.ChecksumMismatchError
+	if !errors.As(err, &mismatchErr) {
+		t.Fatalf("expected ChecksumMismatchError, got: %v", err)
+	}
+
+	if mismatchErr.Version != 2 {
+		t.Fatalf("expected checksum mismatch for version 2, got: %d", mismatchErr.Version)
+	}
+
+	if mismatchErr.Expected == mismatchErr.Actual || mismatchErr.Actual == "" {
+		t.Fatalf("unexpected checksums: %s and %s", mismatchErr.Expected, mismatchErr.Actual)
+	}
+
+	m, err = migrator.New(db, fsys, migrator.WithoutChecksumVerification())
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	_, err = m.Plan()
+	if !errors.As(err, &mismatchErr) {
+		t.Fatalf("expected ChecksumMismatchError from Plan, got: %v", err)
+	}
+
+	err = m.Migrate()
+	if !errors.As(err, &mismatchErr) {
+		t.Fatalf("expected ChecksumMismatchError from Migrate, got: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 3 {
+		t.Fatalf("expected version 3, got: %d", version)
+	}
+
+	// accept the new checksum
+	err = m.Repair()
+	if err != nil {
+		t.Fatalf("failed to repair checksums: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations after repair: %v", err)
+	}
+
+	version, err = m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 4 {
+		t.Fatalf("expected version 4, got: %d", version)
+	}
+}
+
+func TestMigrate_ChecksumWhitespace(t *testing.T) {
+	// blank lines around statements do not change the checksum
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsOKFS)
+	defer db.Close()
+
+	err := m.MigrateTo(3)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	fsys := modifiedFS(
+		t,
+		migrationsOKFS,
+		"2_change_table.sql",
+		"-- +migrate Up\n\n  ALTER TABLE test_table ADD COLUMN description TEXT;  \n\n",
+	)
+	m = getMigrator(t, db, fsys)
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+}
+
+func TestRepair_LegacyTable(t *testing.T) {
+	// migrations applied before checksums existed get one
+	t.Parallel()
+
+	db, m := getDBAndMigrator(
+		t,
+		migrationsOKFS,
+		`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY)`,
+		`INSERT INTO schema_migrations (version) VALUES (1)`,
+		`CREATE TABLE test_table (id INTEGER PRIMARY KEY, name TEXT)`,
+	)
+	defer db.Close()
+
+	err := m.Repair()
+	if err != nil {
+		t.Fatalf("failed to repair checksums: %v", err)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE checksum IS NOT NULL`).
+		Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count checksums: %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected one checksum, got: %d", count)
+	}
+}
+
+func TestMigrate_ChecksumComment(t *testing.T) {
+	// comments are part of the statements, changing them changes the checksum
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsOKFS)
+	defer db.Close()
+
+	err := m.MigrateTo(3)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	fsys := modifiedFS(
+		t,
+		migrationsOKFS,
+		"2_change_table.sql",
+		"-- +migrate Up\n-- add a description\nALTER TABLE test_table ADD COLUMN description TEXT;\n",
+	)
+
+	_, err = migrator.New(db, fsys)
+
+	var mismatchErr migrator.ChecksumMismatchError
+	if !errors.As(err, &mismatchErr) {
+		t.Fatalf("expected ChecksumMismatchError, got: %v", err)
+	}
+}
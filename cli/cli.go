@@ -0,0 +1,495 @@
+// Package cli implements the migrator command, applying migrations on a database.
+//
+// The migrator command includes only the SQLite driver. To use another database, build your
+// own binary importing the corresponding driver and calling Main:
+//
+//	package main
+//
+//	import (
+//		"os"
+//
+//		_ "github.com/jackc/pgx/v5/stdlib"
+//
+//		"github.com/erdnaxeli/migrator/cli"
+//	)
+//
+//	func main() {
+//		os.Exit(cli.Main(os.Args[1:]))
+//	}
+//
+// It is then used with the flags -driver pgx -dialect postgres.
+package cli
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+var (
+	errMissingCommand = errors.New("missing command")
+	errMissingDSN     = errors.New("missing -dsn flag")
+	errInvalidVar     = errors.New("expected name=value")
+	errInvalidArgs    = errors.New("invalid arguments")
+	errInvalidDialect = errors.New("invalid dialect, expected sqlite, postgres, mysql or sqlserver")
+)
+
+// dialects are the dialects of the -dialect flag.
+var dialects = map[string]migrator.Dialect{
+	"sqlite":    migrator.SQLite,
+	"postgres":  migrator.PostgreSQL,
+	"mysql":     migrator.MySQL,
+	"sqlserver": migrator.SQLServer,
+}
+
+const usage = `Usage: migrator [flags] <command> [arguments]
+
+Commands:
+  up           apply all pending migrations
+  up-to N      apply or roll back migrations until version N
+  down [N]     roll back the last N migrations, 1 by default
+  status       list the migrations, whether and when they are applied
+  plan         print the pending migrations and their statements
+  baseline N   record the migrations up to version N as applied, without running them
+  version      print the current version of the database
+  repair       accept the checksums of modified applied migrations
+  create NAME  create a new empty migration file
+  validate     check the migration files without connecting to the database
+
+Flags:
+`
+
+// Main runs the migrator command with the given arguments, without the program name, and
+// returns the exit code.
+func Main(args []string) int {
+	err := Run(args, os.Stdout, os.Stderr)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+		}
+
+		return 1
+	}
+
+	return 0
+}
+
+type config struct {
+	driver     string
+	dialect    string
+	dsn        string
+	dir        string
+	table      string
+	schema     string
+	namespace  string
+	sparse     bool
+	outOfOrder bool
+	recursive  bool
+	verbose    bool
+	dryRun     bool
+	json       bool
+	vars       map[string]any
+
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run runs the migrator command with the given arguments, without the program name.
+func Run(args []string, stdout io.Writer, stderr io.Writer) error {
+	c := config{stdout: stdout, stderr: stderr}
+
+	flags := flag.NewFlagSet("migrator", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&c.driver, "driver", "sqlite", "database/sql driver name")
+	flags.StringVar(
+		&c.dialect,
+		"dialect",
+		"sqlite",
+		"SQL dialect of the history table queries: sqlite, postgres, mysql or sqlserver",
+	)
+	flags.StringVar(&c.dsn, "dsn", "", "data source name of the database")
+	flags.StringVar(&c.dir, "dir", "migrations", "directory containing the migration files")
+	flags.StringVar(&c.table, "table", "schema_migrations", "name of the history table")
+	flags.StringVar(&c.schema, "schema", "", "schema of the history table")
+	flags.StringVar(&c.namespace, "namespace", "", "namespace of the migrations")
+	flags.BoolVar(
+		&c.sparse,
+		"sparse",
+		false,
+		"allow gaps between versions, create uses a timestamp as version",
+	)
+	flags.BoolVar(
+		&c.outOfOrder,
+		"out-of-order",
+		false,
+		"with -sparse, apply migrations older than the latest applied one",
+	)
+	flags.BoolVar(
+		&c.recursive,
+		"recursive",
+		false,
+		"search migration files in the subdirectories of -dir too",
+	)
+	flags.BoolVar(&c.verbose, "v", false, "log migration events")
+	flags.BoolVar(
+		&c.dryRun,
+		"dry-run",
+		false,
+		"log the statements of up, up-to and down instead of executing them, implies -v",
+	)
+	flags.BoolVar(&c.json, "json", false, "print the status or the plan as JSON")
+	flags.Func(
+		"var",
+		"set a `name=value` variable for the .sql.tmpl migration templates, can be repeated",
+		c.setVar,
+	)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := dialects[c.dialect]; !ok {
+		return fmt.Errorf("%w: %s", errInvalidDialect, c.dialect)
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errMissingCommand
+	}
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "create":
+		return c.create(args)
+	case "validate":
+		return c.validate(args)
+	case "up", "up-to", "down", "status", "plan", "baseline", "version", "repair":
+		return c.withMigrator(command, args)
+	default:
+		flags.Usage()
+		return fmt.Errorf("%w: unknown command %s", errInvalidArgs, command)
+	}
+}
+
+func (c config) withMigrator(command string, args []string) error {
+	if c.dsn == "" {
+		return errMissingDSN
+	}
+
+	db, err := sql.Open(c.driver, c.dsn)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	logger := slog.New(slog.DiscardHandler)
+	if c.verbose || c.dryRun {
+		logger = slog.New(slog.NewTextHandler(c.stderr, nil))
+	}
+
+	opts := append(
+		c.options(),
+		migrator.WithLogger(logger),
+		migrator.WithDialect(dialects[c.dialect]),
+		migrator.WithTableName(c.table),
+		migrator.WithSchema(c.schema),
+		migrator.WithNamespace(c.namespace),
+	)
+	if c.dryRun {
+		opts = append(opts, migrator.WithDryRun())
+	}
+
+	if command == "repair" {
+		opts = append(opts, migrator.WithoutChecksumVerification())
+	}
+
+	m, err := migrator.New(db, os.DirFS(c.dir), opts...)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		err = c.up(m, args)
+	case "up-to":
+		err = c.upTo(m, args)
+	case "down":
+		err = c.down(m, args)
+	case "status":
+		err = c.status(m, args)
+	case "plan":
+		err = c.plan(m, args)
+	case "baseline":
+		err = c.baseline(m, args)
+	case "repair":
+		err = c.repair(m, args)
+	default:
+		err = c.version(m, args)
+	}
+
+	return err
+}
+
+// options returns the options needed to load the migrations.
+func (c config) options() []migrator.Option {
+	var opts []migrator.Option
+	if c.sparse {
+		opts = append(opts, migrator.WithSparseVersions())
+	}
+
+	if c.outOfOrder {
+		opts = append(opts, migrator.WithOutOfOrder())
+	}
+
+	if c.recursive {
+		opts = append(opts, migrator.WithRecursive())
+	}
+
+	if c.vars != nil {
+		opts = append(opts, migrator.WithTemplateData(c.vars))
+	}
+
+	return opts
+}
+
+// setVar parses a -var flag.
+func (c *config) setVar(value string) error {
+	name, value, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return errInvalidVar
+	}
+
+	if c.vars == nil {
+		c.vars = make(map[string]any)
+	}
+
+	c.vars[name] = value
+	return nil
+}
+
+func (c config) up(m migrator.Migrator, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("%w: up takes no arguments", errInvalidArgs)
+	}
+
+	err := m.Migrate()
+	if err != nil {
+		return err
+	}
+
+	return c.version(m, nil)
+}
+
+func (c config) upTo(m migrator.Migrator, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: up-to takes a version", errInvalidArgs)
+	}
+
+	version, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid version %s", errInvalidArgs, args[0])
+	}
+
+	err = m.MigrateTo(version)
+	if err != nil {
+		return err
+	}
+
+	return c.version(m, nil)
+}
+
+func (c config) down(m migrator.Migrator, args []string) error {
+	steps := 1
+	if len(args) > 1 {
+		return fmt.Errorf("%w: down takes at most one argument", errInvalidArgs)
+	}
+
+	if len(args) == 1 {
+		var err error
+		steps, err = strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("%w: invalid number of steps %s", errInvalidArgs, args[0])
+		}
+	}
+
+	err := m.Rollback(steps)
+	if err != nil {
+		return err
+	}
+
+	return c.version(m, nil)
+}
+
+func (c config) status(m migrator.Migrator, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("%w: status takes no arguments", errInvalidArgs)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return migrator.WriteStatusJSON(c.stdout, statuses)
+	}
+
+	return migrator.WriteStatusTable(c.stdout, statuses)
+}
+
+func (c config) plan(m migrator.Migrator, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("%w: plan takes no arguments", errInvalidArgs)
+	}
+
+	plan, err := m.Plan()
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	}
+
+	for _, planned := range plan {
+		mode := "transaction"
+		if !planned.Transactional {
+			mode = "no transaction"
+		}
+
+		if planned.Baseline {
+			mode += ", baseline"
+		}
+
+		_, err = fmt.Fprintf(c.stdout, "%d %s (%s)\n", planned.Version, planned.Name, mode)
+		if err != nil {
+			return err
+		}
+
+		for _, statement := range planned.Statements {
+			_, err = fmt.Fprintf(c.stdout, "    %s\n", strings.ReplaceAll(statement, "\n", "\n    "))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c config) baseline(m migrator.Migrator, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: baseline takes a version", errInvalidArgs)
+	}
+
+	version, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid version %s", errInvalidArgs, args[0])
+	}
+
+	err = m.Baseline(version)
+	if err != nil {
+		return err
+	}
+
+	return c.version(m, nil)
+}
+
+func (c config) repair(m migrator.Migrator, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("%w: repair takes no arguments", errInvalidArgs)
+	}
+
+	return m.Repair()
+}
+
+func (c config) version(m migrator.Migrator, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("%w: version takes no arguments", errInvalidArgs)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.stdout, version)
+	return err
+}
+
+func (c config) create(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: create takes a name", errInvalidArgs)
+	}
+
+	latest, err := migrator.LatestVersion(os.DirFS(c.dir), c.options()...)
+	if err != nil {
+		return err
+	}
+
+	version := latest + 1
+	if c.sparse {
+		version, err = strconv.ParseInt(time.Now().UTC().Format("20060102150405"), 10, 64)
+		if err != nil {
+			return err
+		}
+	}
+
+	if args[0] == "" || strings.ContainsAny(args[0], `/\`) {
+		return fmt.Errorf("%w: invalid migration name %s", errInvalidArgs, args[0])
+	}
+
+	filename := filepath.Join(c.dir, fmt.Sprintf("%d_%s.sql", version, args[0]))
+
+	// never overwrite an existing file, like one created at the same second
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString("-- +migrate Up\n\n-- +migrate Down\n")
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.stdout, filename)
+	return err
+}
+
+func (c config) validate(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("%w: validate takes no arguments", errInvalidArgs)
+	}
+
+	err := migrator.Validate(os.DirFS(c.dir), c.options()...)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.stdout, "migrations are valid")
+	return err
+}
@@ -0,0 +1,434 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+// setup copies test migrations in a temporary directory, and returns the flags to use them
+// with a SQLite database in the same directory.
+func setup(t *testing.T, migrations string) (string, []string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	migrationsDir := filepath.Join(dir, "migrations")
+
+	err := os.CopyFS(migrationsDir, os.DirFS(filepath.Join("../test_data", migrations)))
+	if err != nil {
+		t.Fatalf("failed to copy migrations: %v", err)
+	}
+
+	return migrationsDir, []string{
+		"-dsn", filepath.Join(dir, "db.sqlite"),
+		"-dir", migrationsDir,
+	}
+}
+
+func runCommand(t *testing.T, flags []string, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	err := Run(append(flags, args...), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+func TestRun_UpAndDown(t *testing.T) {
+	t.Parallel()
+
+	_, flags := setup(t, "migrations_down")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"version"}, expected: "0\n"},
+		{args: []string{"up"}, expected: "3\n"},
+		{args: []string{"down"}, expected: "2\n"},
+		{args: []string{"down", "2"}, expected: "0\n"},
+		{args: []string{"up-to", "2"}, expected: "2\n"},
+		{args: []string{"version"}, expected: "2\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, err := runCommand(t, flags, tt.args...)
+		if err != nil {
+			t.Fatalf("command %v failed: %v", tt.args, err)
+		}
+
+		if stdout != tt.expected {
+			t.Fatalf("command %v: expected output %q, got: %q", tt.args, tt.expected, stdout)
+		}
+	}
+}
+
+func TestRun_Status(t *testing.T) {
+	t.Parallel()
+
+	_, flags := setup(t, "migrations_down")
+
+	_, err := runCommand(t, flags, "up-to", "2")
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	stdout, err := runCommand(t, flags, "status")
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header and three migrations, got: %q", stdout)
+	}
+
+	for i, prefix := range []string{
+		"1        test_table          applied  20",
+		"2        change_table        applied  20",
+		"3        another_test_table  pending",
+	} {
+		if !strings.HasPrefix(lines[i+1], prefix) {
+			t.Errorf("expected line %q to start with %q", lines[i+1], prefix)
+		}
+	}
+
+	stdout, err = runCommand(t, append(flags, "-json"), "status")
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	var statuses []migrator.MigrationStatus
+	err = json.Unmarshal([]byte(stdout), &statuses)
+	if err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+
+	if len(statuses) != 3 || !statuses[1].Applied || statuses[2].Applied {
+		t.Fatalf("unexpected status: %+v", statuses)
+	}
+}
+
+func TestRun_Plan(t *testing.T) {
+	t.Parallel()
+
+	_, flags := setup(t, "migrations_down")
+
+	_, err := runCommand(t, flags, "up-to", "1")
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	stdout, err := runCommand(t, flags, "plan")
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+
+	expected := `2 change_table (transaction)
+    ALTER TABLE test_table ADD COLUMN description TEXT;
+3 another_test_table (transaction)
+    CREATE TABLE another_test_table (
+        id INTEGER PRIMARY KEY,
+        name TEXT NOT NULL
+    );
+    INSERT INTO another_test_table (id, name) VALUES (1, 'Test Name 1');
+`
+	if stdout != expected {
+		t.Fatalf("expected output %q, got: %q", expected, stdout)
+	}
+
+	stdout, err = runCommand(t, append(flags, "-json"), "plan")
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+
+	var plan []migrator.PlannedMigration
+	err = json.Unmarshal([]byte(stdout), &plan)
+	if err != nil {
+		t.Fatalf("failed to decode plan: %v", err)
+	}
+
+	if len(plan) != 2 || plan[0].Version != 2 || len(plan[1].Statements) != 2 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	stdout, err = runCommand(t, append(flags, "-dry-run"), "up")
+	if err != nil {
+		t.Fatalf("failed to run a dry run: %v", err)
+	}
+
+	if stdout != "1\n" {
+		t.Fatalf("expected the version to stay 1, got: %q", stdout)
+	}
+}
+
+func TestRun_Baseline(t *testing.T) {
+	t.Parallel()
+
+	_, flags := setup(t, "migrations_down")
+
+	stdout, err := runCommand(t, flags, "baseline", "3")
+	if err != nil {
+		t.Fatalf("failed to baseline: %v", err)
+	}
+
+	if stdout != "3\n" {
+		t.Fatalf("expected output %q, got: %q", "3\n", stdout)
+	}
+
+	stdout, err = runCommand(t, flags, "plan")
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+
+	if stdout != "" {
+		t.Fatalf("expected no pending migration, got: %q", stdout)
+	}
+}
+
+func TestRun_Repair(t *testing.T) {
+	t.Parallel()
+
+	dir, flags := setup(t, "migrations_down")
+
+	_, err := runCommand(t, flags, "up")
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	err = os.WriteFile(
+		filepath.Join(dir, "1_test_table.sql"),
+		[]byte("-- +migrate Up\nCREATE TABLE test_table (id INTEGER PRIMARY KEY);\n"),
+		0o600,
+	)
+	if err != nil {
+		t.Fatalf("failed to modify migration: %v", err)
+	}
+
+	_, err = runCommand(t, flags, "up")
+
+	var mismatchErr migrator.ChecksumMismatchError
+	if !errors.As(err, &mismatchErr) {
+		t.Fatalf("expected ChecksumMismatchError, got: %v", err)
+	}
+
+	_, err = runCommand(t, flags, "repair")
+	if err != nil {
+		t.Fatalf("failed to repair: %v", err)
+	}
+
+	_, err = runCommand(t, flags, "up")
+	if err != nil {
+		t.Fatalf("failed to migrate after repair: %v", err)
+	}
+}
+
+func TestRun_Create(t *testing.T) {
+	t.Parallel()
+
+	dir, flags := setup(t, "migrations_down")
+
+	stdout, err := runCommand(t, flags, "create", "add_users")
+	if err != nil {
+		t.Fatalf("failed to create migration: %v", err)
+	}
+
+	filename := filepath.Join(dir, "4_add_users.sql")
+	if stdout != filename+"\n" {
+		t.Fatalf("expected output %q, got: %q", filename+"\n", stdout)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read created migration: %v", err)
+	}
+
+	if !strings.HasPrefix(string(content), "-- +migrate Up\n") {
+		t.Fatalf("unexpected migration content: %q", content)
+	}
+
+	// the new migration is empty but valid
+	_, err = runCommand(t, flags, "up")
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+}
+
+func TestRun_CreateRecursive(t *testing.T) {
+	// templates and the migrations in subdirectories are found like when migrating
+	t.Parallel()
+
+	dir, flags := setup(t, "migrations_down")
+	flags = append(flags, "-recursive")
+
+	files := map[string]string{
+		"4_orders.sql.tmpl": "-- +migrate Up\n",
+		"sub/5_items.sql":   "-- +migrate Up\n",
+	}
+	for filename, content := range files {
+		path := filepath.Join(dir, filename)
+
+		err := os.MkdirAll(filepath.Dir(path), 0o700)
+		if err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write migration: %v", err)
+		}
+	}
+
+	stdout, err := runCommand(t, flags, "create", "add_users")
+	if err != nil {
+		t.Fatalf("failed to create migration: %v", err)
+	}
+
+	filename := filepath.Join(dir, "6_add_users.sql")
+	if stdout != filename+"\n" {
+		t.Fatalf("expected output %q, got: %q", filename+"\n", stdout)
+	}
+}
+
+func TestRun_CreateSparse(t *testing.T) {
+	t.Parallel()
+
+	dir, flags := setup(t, "migrations_down")
+	flags = append(flags, "-sparse")
+
+	stdout, err := runCommand(t, flags, "create", "add_users")
+	if err != nil {
+		t.Fatalf("failed to create migration: %v", err)
+	}
+
+	filename := filepath.Base(strings.TrimSpace(stdout))
+	if !regexp.MustCompile(`^\d{14}_add_users\.sql$`).MatchString(filename) {
+		t.Fatalf("expected a timestamp version, got: %s", filename)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, filename))
+	if err != nil {
+		t.Fatalf("failed to find created migration: %v", err)
+	}
+
+	_, err = runCommand(t, flags, "validate")
+	if err != nil {
+		t.Fatalf("expected migrations to be valid, got: %v", err)
+	}
+
+	stdout, err = runCommand(t, flags, "up")
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	expected := strings.TrimSuffix(filename, "_add_users.sql") + "\n"
+	if stdout != expected {
+		t.Fatalf("expected output %q, got: %q", expected, stdout)
+	}
+}
+
+func TestRun_Template(t *testing.T) {
+	t.Parallel()
+
+	dir, flags := setup(t, "migrations_ok")
+
+	err := os.WriteFile(
+		filepath.Join(dir, "5_tenant.sql.tmpl"),
+		[]byte("-- +migrate Up\nCREATE TABLE {{.prefix}}orders (id INTEGER PRIMARY KEY);\n"),
+		0o644,
+	)
+	if err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	_, err = runCommand(t, flags, "up")
+	if err == nil {
+		t.Fatal("expected an error without the template variable")
+	}
+
+	stdout, err := runCommand(t, append(flags, "-var", "prefix=acme_"), "plan")
+	if err != nil {
+		t.Fatalf("failed to plan migrations: %v", err)
+	}
+
+	if !strings.Contains(stdout, "CREATE TABLE acme_orders") {
+		t.Fatalf("expected the rendered statement, got: %q", stdout)
+	}
+
+	_, err = runCommand(t, append(flags, "-var", "prefix"), "plan")
+	if err == nil {
+		t.Fatal("expected an error for an invalid variable")
+	}
+}
+
+func TestRun_Validate(t *testing.T) {
+	t.Parallel()
+
+	_, flags := setup(t, "migrations_ok")
+
+	stdout, err := runCommand(t, flags, "validate")
+	if err != nil {
+		t.Fatalf("failed to validate migrations: %v", err)
+	}
+
+	if stdout != "migrations are valid\n" {
+		t.Fatalf("unexpected output: %q", stdout)
+	}
+}
+
+func TestRun_Validate_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, flags := setup(t, "duplicated_version")
+
+	_, err := runCommand(t, flags, "validate")
+
+	var dupErr migrator.DuplicateMigrationVersionError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected DuplicateMigrationVersionError, got: %v", err)
+	}
+}
+
+func TestRun_MissingDSN(t *testing.T) {
+	t.Parallel()
+
+	_, err := runCommand(t, nil, "up")
+	if !errors.Is(err, errMissingDSN) {
+		t.Fatalf("expected errMissingDSN, got: %v", err)
+	}
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	t.Parallel()
+
+	_, flags := setup(t, "migrations_ok")
+
+	_, err := runCommand(t, flags, "sideways")
+	if !errors.Is(err, errInvalidArgs) {
+		t.Fatalf("expected errInvalidArgs, got: %v", err)
+	}
+}
+
+func TestRun_InvalidDialect(t *testing.T) {
+	t.Parallel()
+
+	_, flags := setup(t, "migrations_ok")
+
+	_, err := runCommand(t, append(flags, "-dialect", "oracle"), "up")
+	if !errors.Is(err, errInvalidDialect) {
+		t.Fatalf("expected errInvalidDialect, got: %v", err)
+	}
+
+	_, err = runCommand(t, append(flags, "-dialect", "sqlite"), "up")
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+// Command migrator applies migrations on a database.
+//
+// Usage:
+//
+//	migrator [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	up          apply all pending migrations
+//	up-to N     apply or roll back migrations until version N
+//	down [N]    roll back the last N migrations, 1 by default
+//	status      list the migrations, whether and when they are applied
+//	plan        print the pending migrations and their statements
+//	baseline N  record the migrations up to version N as applied, without running them
+//	version     print the current version of the database
+//	repair      accept the checksums of modified applied migrations
+//	create NAME create a new empty migration file
+//	validate    check the migration files without connecting to the database
+//
+// Only the SQLite driver is included. To use another database, build your own binary
+// importing the corresponding driver and calling cli.Main, see package
+// github.com/erdnaxeli/migrator/cli.
+package main
+
+import (
+	"os"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/erdnaxeli/migrator/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(os.Args[1:]))
+}
@@ -0,0 +1,172 @@
+package migrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultHistoryTable is the default name of the table recording applied migrations.
+const defaultHistoryTable = "schema_migrations"
+
+// Dialect describes the SQL specificities of a database engine.
+//
+// It is only used for the queries on the history table, migrations are executed as is.
+type Dialect interface {
+	// Placeholder returns the bind parameter for the n-th argument of a query, starting at 1.
+	Placeholder(n int) string
+
+	// QuoteIdentifier quotes a table or schema name, so it can contain any character and is
+	// case sensitive.
+	QuoteIdentifier(name string) string
+
+	// CreateHistoryTableSQL returns the statement creating the history table.
+	//
+	// The table name is already quoted and qualified.
+	CreateHistoryTableSQL(table string) string
+
+	// AddColumnSQL returns the statement adding a nullable column to a table.
+	//
+	// The table name is already quoted and qualified.
+	AddColumnSQL(table string, column string, definition string) string
+
+	// TableExistsSQL returns the query counting the tables with a given name in the catalog.
+	//
+	// Its arguments are the schema, empty for the current one, and the table name, not quoted.
+	TableExistsSQL() string
+}
+
+var (
+	// SQLite is the dialect for SQLite. This is the default dialect.
+	SQLite Dialect = sqliteDialect{}
+	// PostgreSQL is the dialect for PostgreSQL.
+	PostgreSQL Dialect = postgresDialect{}
+	// MySQL is the dialect for MySQL and MariaDB.
+	MySQL Dialect = mysqlDialect{}
+	// SQLServer is the dialect for Microsoft SQL Server.
+	SQLServer Dialect = sqlServerDialect{}
+)
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (sqliteDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (sqliteDialect) CreateHistoryTableSQL(table string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE %s ("+
+			"namespace VARCHAR(255) NOT NULL DEFAULT '', "+
+			"version INTEGER NOT NULL, "+
+			"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, "+
+			"checksum VARCHAR(64), "+
+			"PRIMARY KEY (namespace, version))",
+		table,
+	)
+}
+
+func (sqliteDialect) AddColumnSQL(table string, column string, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+}
+
+func (sqliteDialect) TableExistsSQL() string {
+	return "SELECT COUNT(*) FROM pragma_table_list " +
+		"WHERE schema = COALESCE(NULLIF(?, ''), 'main') AND name = ? AND type = 'table'"
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (postgresDialect) CreateHistoryTableSQL(table string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE %s ("+
+			"namespace VARCHAR(255) NOT NULL DEFAULT '', "+
+			"version BIGINT NOT NULL, "+
+			"applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP, "+
+			"checksum VARCHAR(64), "+
+			"PRIMARY KEY (namespace, version))",
+		table,
+	)
+}
+
+func (postgresDialect) AddColumnSQL(table string, column string, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+}
+
+func (postgresDialect) TableExistsSQL() string {
+	return "SELECT COUNT(*) FROM information_schema.tables " +
+		"WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2"
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (mysqlDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (mysqlDialect) CreateHistoryTableSQL(table string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE %s ("+
+			"namespace VARCHAR(255) NOT NULL DEFAULT '', "+
+			"version BIGINT NOT NULL, "+
+			"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, "+
+			"checksum VARCHAR(64), "+
+			"PRIMARY KEY (namespace, version))",
+		table,
+	)
+}
+
+func (mysqlDialect) AddColumnSQL(table string, column string, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+}
+
+func (mysqlDialect) TableExistsSQL() string {
+	return "SELECT COUNT(*) FROM information_schema.tables " +
+		"WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?"
+}
+
+type sqlServerDialect struct{}
+
+func (sqlServerDialect) Placeholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
+
+func (sqlServerDialect) QuoteIdentifier(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+func (sqlServerDialect) CreateHistoryTableSQL(table string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE %s ("+
+			"namespace VARCHAR(255) NOT NULL DEFAULT '', "+
+			"version BIGINT NOT NULL, "+
+			"applied_at DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(), "+
+			"checksum VARCHAR(64), "+
+			"PRIMARY KEY (namespace, version))",
+		table,
+	)
+}
+
+func (sqlServerDialect) AddColumnSQL(table string, column string, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s %s", table, column, definition)
+}
+
+func (sqlServerDialect) TableExistsSQL() string {
+	return "SELECT COUNT(*) FROM information_schema.tables " +
+		"WHERE table_schema = COALESCE(NULLIF(@p1, ''), SCHEMA_NAME()) AND table_name = @p2"
+}
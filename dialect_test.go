@@ -0,0 +1,178 @@
+package migrator_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+func TestDialects(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		dialect   migrator.Dialect
+		create    string
+		insert    string
+		delete    string
+		quote     string
+		addColumn string
+		exists    string
+	}{
+		{
+			name:    "sqlite",
+			dialect: migrator.SQLite,
+			create: `CREATE TABLE "schema_migrations" (` +
+				"namespace VARCHAR(255) NOT NULL DEFAULT '', " +
+				"version INTEGER NOT NULL, " +
+				"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+				"checksum VARCHAR(64), " +
+				"PRIMARY KEY (namespace, version))",
+			insert:    `INSERT INTO "schema_migrations" (namespace, version, checksum) VALUES (?, ?, ?)`,
+			delete:    `DELETE FROM "schema_migrations" WHERE namespace = ? AND version = ?`,
+			quote:     `"a.""b""]c"`,
+			addColumn: "ALTER TABLE t ADD COLUMN c TEXT",
+			exists: "SELECT COUNT(*) FROM pragma_table_list " +
+				"WHERE schema = COALESCE(NULLIF(?, ''), 'main') AND name = ? AND type = 'table'",
+		},
+		{
+			name:    "postgresql",
+			dialect: migrator.PostgreSQL,
+			create: `CREATE TABLE "schema_migrations" (` +
+				"namespace VARCHAR(255) NOT NULL DEFAULT '', " +
+				"version BIGINT NOT NULL, " +
+				"applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+				"checksum VARCHAR(64), " +
+				"PRIMARY KEY (namespace, version))",
+			insert:    `INSERT INTO "schema_migrations" (namespace, version, checksum) VALUES ($1, $2, $3)`,
+			delete:    `DELETE FROM "schema_migrations" WHERE namespace = $1 AND version = $2`,
+			quote:     `"a.""b""]c"`,
+			addColumn: "ALTER TABLE t ADD COLUMN c TEXT",
+			exists: "SELECT COUNT(*) FROM information_schema.tables " +
+				"WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) " +
+				"AND table_name = $2",
+		},
+		{
+			name:    "mysql",
+			dialect: migrator.MySQL,
+			create: "CREATE TABLE `schema_migrations` (" +
+				"namespace VARCHAR(255) NOT NULL DEFAULT '', " +
+				"version BIGINT NOT NULL, " +
+				"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+				"checksum VARCHAR(64), " +
+				"PRIMARY KEY (namespace, version))",
+			insert:    "INSERT INTO `schema_migrations` (namespace, version, checksum) VALUES (?, ?, ?)",
+			delete:    "DELETE FROM `schema_migrations` WHERE namespace = ? AND version = ?",
+			quote:     "`a.\"b\"]c`",
+			addColumn: "ALTER TABLE t ADD COLUMN c TEXT",
+			exists: "SELECT COUNT(*) FROM information_schema.tables " +
+				"WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?",
+		},
+		{
+			name:    "sqlserver",
+			dialect: migrator.SQLServer,
+			create: "CREATE TABLE [schema_migrations] (" +
+				"namespace VARCHAR(255) NOT NULL DEFAULT '', " +
+				"version BIGINT NOT NULL, " +
+				"applied_at DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(), " +
+				"checksum VARCHAR(64), " +
+				"PRIMARY KEY (namespace, version))",
+			insert: "INSERT INTO [schema_migrations] (namespace, version, checksum) " +
+				"VALUES (@p1, @p2, @p3)",
+			delete:    "DELETE FROM [schema_migrations] WHERE namespace = @p1 AND version = @p2",
+			quote:     `[a."b"]]c]`,
+			addColumn: "ALTER TABLE t ADD c TEXT",
+			exists: "SELECT COUNT(*) FROM information_schema.tables " +
+				"WHERE table_schema = COALESCE(NULLIF(@p1, ''), SCHEMA_NAME()) " +
+				"AND table_name = @p2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, recorder := newFakeDB()
+			defer db.Close()
+
+			m, err := migrator.New(db, migrationsDownFS, migrator.WithDialect(tt.dialect))
+			if err != nil {
+				t.Fatalf("failed to create migrator: %v", err)
+			}
+
+			err = m.Migrate()
+			if err != nil {
+				t.Fatalf("failed to apply migrations: %v", err)
+			}
+
+			err = m.Rollback(1)
+			if err != nil {
+				t.Fatalf("failed to roll back migration: %v", err)
+			}
+
+			statements := recorder.Statements()
+			for _, expected := range []string{tt.create, tt.insert, tt.delete, tt.exists} {
+				if !slices.Contains(statements, expected) {
+					t.Fatalf("expected statement %q, got: %q", expected, statements)
+				}
+			}
+
+			quote := tt.dialect.QuoteIdentifier(`a."b"]c`)
+			if quote != tt.quote {
+				t.Fatalf("expected identifier %s, got: %s", tt.quote, quote)
+			}
+
+			addColumn := tt.dialect.AddColumnSQL("t", "c", "TEXT")
+			if addColumn != tt.addColumn {
+				t.Fatalf("expected statement %q, got: %q", tt.addColumn, addColumn)
+			}
+		})
+	}
+}
+
+func TestNew_TableName(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(
+		db,
+		migrationsOKFS,
+		migrator.WithSchema("main"),
+		migrator.WithTableName("app.Migrations"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	var version int
+	err = db.QueryRow(`SELECT MAX(version) FROM main."app.Migrations"`).Scan(&version)
+	if err != nil {
+		t.Fatalf("failed to read history table: %v", err)
+	}
+
+	if version != 4 {
+		t.Fatalf("expected version 4, got: %d", version)
+	}
+
+	_, err = db.Exec(`SELECT 1 FROM schema_migrations`)
+	if err == nil {
+		t.Fatalf("expected schema_migrations table to not exist, but it does")
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 4 || !statuses[3].Applied {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+}
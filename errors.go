@@ -23,24 +23,72 @@ func (e EmptyMigrationError) Error() string {
 // InvalidMigrationFileError is returned when a migration file foramt is invalid.
 type InvalidMigrationFileError struct {
 	Filename string
+	// Line is the invalid line, starting at 1.
+	Line int
+	// Reason tells what is invalid, like a missing "-- +migrate Up" first line or a second
+	// "-- +migrate Down" line.
+	Reason string
 }
 
 func (e InvalidMigrationFileError) Error() string {
-	return "invalid migration file: " + e.Filename + ", first line must be \"-- +migrate Up\""
+	return fmt.Sprintf("invalid migration file %s at line %d: %s", e.Filename, e.Line, e.Reason)
+}
+
+// UnbalancedStatementMarkerError is returned when a "-- +migrate StatementBegin" marker is not
+// followed by a "-- +migrate StatementEnd" one, or the opposite.
+type UnbalancedStatementMarkerError struct {
+	Filename string
+	Line     int
+}
+
+func (e UnbalancedStatementMarkerError) Error() string {
+	return fmt.Sprintf("unbalanced statement marker in %s at line %d", e.Filename, e.Line)
+}
+
+// TemplateError is returned when a migration template cannot be parsed or rendered.
+type TemplateError struct {
+	Filename string
+	// Line is the line of the error in the template, or 0 if it is unknown.
+	Line int
+	Err  error
+}
+
+func (e TemplateError) Error() string {
+	return fmt.Sprintf("template error in %s at line %d: %v", e.Filename, e.Line, e.Err)
+}
+
+func (e TemplateError) Unwrap() error {
+	return e.Err
 }
 
 // DuplicateMigrationVersionError is returned when there are multiple migrations with the same version.
 type DuplicateMigrationVersionError struct {
-	Version int
+	Version int64
+	// Filenames are the paths of the two migrations in the fs.FS, empty for Go migrations.
+	// Paths in a source added with WithFS are prefixed by its name, like "plugin:1_init.sql".
+	Filenames [2]string
 }
 
 func (e DuplicateMigrationVersionError) Error() string {
-	return fmt.Sprintf("duplicate migration version: %d", e.Version)
+	if e.Filenames == [2]string{} {
+		return fmt.Sprintf("duplicate migration version: %d", e.Version)
+	}
+
+	filenames := e.Filenames
+	for i, filename := range filenames {
+		if filename == "" {
+			filenames[i] = "Go migration"
+		}
+	}
+
+	return fmt.Sprintf(
+		"duplicate migration version: %d, in %s and %s", e.Version, filenames[0], filenames[1],
+	)
 }
 
 // MissingMigrationVersionError is returned when a migration version is missing in the sequence.
 type MissingMigrationVersionError struct {
-	Version int
+	Version int64
 }
 
 func (e MissingMigrationVersionError) Error() string {
@@ -49,9 +97,158 @@ func (e MissingMigrationVersionError) Error() string {
 
 // InvalidCurrentVersionError is returned when the current database version does not correspond to any migration.
 type InvalidCurrentVersionError struct {
-	Version int
+	Version int64
 }
 
 func (e InvalidCurrentVersionError) Error() string {
 	return fmt.Sprintf("invalid current database version: %d", e.Version)
 }
+
+// InvalidTargetVersionError is returned when a target version does not correspond to any migration.
+type InvalidTargetVersionError struct {
+	Version int64
+}
+
+func (e InvalidTargetVersionError) Error() string {
+	return fmt.Sprintf("invalid target version: %d", e.Version)
+}
+
+// InvalidRollbackStepsError is returned when the number of migrations to roll back is negative
+// or greater than the number of applied migrations.
+type InvalidRollbackStepsError struct {
+	Steps int
+	// Applied is the number of applied migrations.
+	Applied int
+}
+
+func (e InvalidRollbackStepsError) Error() string {
+	return fmt.Sprintf(
+		"cannot roll back %d migrations, %d migrations are applied", e.Steps, e.Applied,
+	)
+}
+
+// OutOfOrderMigrationError is returned when a pending migration is older than the latest
+// applied migration, with sparse versions.
+type OutOfOrderMigrationError struct {
+	Version int64
+	// LatestVersion is the latest applied version.
+	LatestVersion int64
+}
+
+func (e OutOfOrderMigrationError) Error() string {
+	return fmt.Sprintf(
+		"migration %d is older than the latest applied migration %d",
+		e.Version,
+		e.LatestVersion,
+	)
+}
+
+// SquashedMigrationError is returned when a squash migration is pending on a database that is
+// not empty. The migrations it replaces must be applied with the files they were squashed
+// into, before upgrading to it.
+type SquashedMigrationError struct {
+	// Version is the current version of the database.
+	Version int64
+	// SquashVersion is the version of the squash migration.
+	SquashVersion int64
+}
+
+func (e SquashedMigrationError) Error() string {
+	return fmt.Sprintf(
+		"database version %d is older than squash migration %d, "+
+			"apply the squashed migrations first",
+		e.Version,
+		e.SquashVersion,
+	)
+}
+
+// MissingDownMigrationError is returned when rolling back a migration without a down section,
+// or a squash migration.
+type MissingDownMigrationError struct {
+	Version int64
+}
+
+func (e MissingDownMigrationError) Error() string {
+	return fmt.Sprintf("migration %d has no down section", e.Version)
+}
+
+// MigrationInterruptedError is returned when the context is done while applying or rolling back
+// a migration. The migration in progress is rolled back.
+type MigrationInterruptedError struct {
+	Version int64
+	Err     error
+}
+
+func (e MigrationInterruptedError) Error() string {
+	return fmt.Sprintf("migration %d interrupted: %v", e.Version, e.Err)
+}
+
+func (e MigrationInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// NonTransactionalMigrationError is returned when a statement of a migration run without
+// transaction fails. The previous statements are not rolled back.
+type NonTransactionalMigrationError struct {
+	Version int64
+	// Statement is the number of the failed statement, starting at 1.
+	Statement int
+	Err       error
+}
+
+func (e NonTransactionalMigrationError) Error() string {
+	return fmt.Sprintf(
+		"statement %d of migration %d failed outside of a transaction: %v",
+		e.Statement,
+		e.Version,
+		e.Err,
+	)
+}
+
+func (e NonTransactionalMigrationError) Unwrap() error {
+	return e.Err
+}
+
+// ChecksumMismatchError is returned when the file of an applied migration was modified.
+type ChecksumMismatchError struct {
+	Version int64
+	// Expected is the checksum recorded when the migration was applied.
+	Expected string
+	// Actual is the checksum of the current migration file.
+	Actual string
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(
+		"checksum mismatch for migration %d: expected %s, got %s",
+		e.Version,
+		e.Expected,
+		e.Actual,
+	)
+}
+
+// HistoryTableUpgradeError is returned in dry run mode when the history table was created by an
+// older version, as upgrading it would change the database.
+type HistoryTableUpgradeError struct {
+	Table string
+}
+
+func (e HistoryTableUpgradeError) Error() string {
+	return fmt.Sprintf(
+		"history table %s must be upgraded, which is not done in dry run mode", e.Table,
+	)
+}
+
+// TargetError is returned by MultiRunner when the migration of a target failed.
+type TargetError struct {
+	Target string
+	Err    error
+}
+
+func (e TargetError) Error() string {
+	return fmt.Sprintf("failed to migrate target %s: %v", e.Target, e.Err)
+}
+
+func (e TargetError) Unwrap() error {
+	return e.Err
+}
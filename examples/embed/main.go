@@ -3,8 +3,8 @@ package main
 import (
 	"database/sql"
 	"embed"
-	"io/fs"
 	"log"
+	"log/slog"
 
 	_ "modernc.org/sqlite"
 
@@ -29,13 +29,14 @@ func openDatabaseAndMigrate() (*sql.DB, error) {
 	defer func() { _ = db.Close() }()
 
 	// The migration files are embedded in a folder named "migrations",
-	// so we need to create a sub filesystem starting from there.
-	// Else it will list the root folder and not find any .sql files.
-	subFS, err := fs.Sub(migrationsFS, "migrations")
-	if err != nil {
-		return nil, err
-	}
-	migrator, err := migrator.New(db, subFS)
+	// so they are searched recursively. Else only the root folder is listed
+	// and no .sql files are found. fs.Sub can be used too.
+	migrator, err := migrator.New(
+		db,
+		migrationsFS,
+		migrator.WithRecursive(),
+		migrator.WithLogger(slog.Default()),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"log"
+	"log/slog"
 	"os"
 
 	_ "modernc.org/sqlite"
@@ -25,7 +26,7 @@ func openDatabaseAndMigrate() (*sql.DB, error) {
 	defer func() { _ = db.Close() }()
 
 	directory := os.DirFS("migrations")
-	migrator, err := migrator.New(db, directory)
+	migrator, err := migrator.New(db, directory, migrator.WithLogger(slog.Default()))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,199 @@
+package migrator_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var errFakeNoTable = errors.New("fake: no such table")
+
+// identifierQuotes removes the identifier quotes of every dialect.
+var identifierQuotes = strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "")
+
+// fakeRecorder is a fake database driver recording every statement it receives.
+//
+// Checking a table with "SELECT 1 FROM" or "SELECT ... WHERE 1 = 0" fails, and looking it up in
+// the catalog finds nothing, until a "CREATE TABLE" statement is executed for it.
+// Versions inserted in or deleted from the history table are tracked, so that the current
+// version can be queried, whatever the quoting of the table name. Any other query returns the
+// configured result, or no rows.
+type fakeRecorder struct {
+	mu         sync.Mutex
+	statements []string
+	tables     map[string]bool
+	versions   []int64
+	result     driver.Value
+}
+
+func newFakeDB() (*sql.DB, *fakeRecorder) {
+	recorder := &fakeRecorder{tables: make(map[string]bool)}
+	return sql.OpenDB(recorder), recorder
+}
+
+func (r *fakeRecorder) Statements() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.statements...)
+}
+
+func (r *fakeRecorder) SetResult(value driver.Value) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.result = value
+}
+
+func (r *fakeRecorder) record(query string, args []driver.NamedValue) ([][]driver.Value, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.statements = append(r.statements, query)
+	query = identifierQuotes.Replace(query)
+
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE"):
+		r.tables[tableAfter(query, "CREATE TABLE ")] = true
+	case strings.HasPrefix(query, "DROP TABLE"):
+		delete(r.tables, tableAfter(query, "DROP TABLE "))
+	case (strings.HasPrefix(query, "SELECT 1 FROM") || strings.HasSuffix(query, "WHERE 1 = 0")) &&
+		!r.tables[tableAfter(query, " FROM ")]:
+		return nil, errFakeNoTable
+	case strings.Contains(query, "pragma_table_list") ||
+		strings.Contains(query, "information_schema.tables"):
+		name := args[1].Value.(string)
+		if schema := args[0].Value.(string); schema != "" {
+			name = schema + "." + name
+		}
+
+		if r.tables[name] {
+			return [][]driver.Value{{int64(1)}}, nil
+		}
+
+		return [][]driver.Value{{int64(0)}}, nil
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations "):
+		r.versions = append(r.versions, args[1].Value.(int64))
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations "):
+		r.versions = slices.DeleteFunc(
+			r.versions, func(v int64) bool { return v == args[1].Value.(int64) },
+		)
+	case strings.HasPrefix(query, "SELECT COUNT(*) FROM schema_migrations WHERE namespace ="):
+		count := 0
+		for _, v := range r.versions {
+			if v >= args[1].Value.(int64) {
+				count++
+			}
+		}
+
+		return [][]driver.Value{{int64(count)}}, nil
+	case strings.HasPrefix(query, "SELECT MAX(version)"):
+		if len(r.versions) == 0 {
+			return [][]driver.Value{{nil}}, nil
+		}
+
+		return [][]driver.Value{{slices.Max(r.versions)}}, nil
+	case r.result != nil:
+		return [][]driver.Value{{r.result}}, nil
+	}
+
+	return nil, nil
+}
+
+// tableAfter returns the table name following a keyword in a query.
+func tableAfter(query string, keyword string) string {
+	_, after, _ := strings.Cut(query, keyword)
+	name, _, _ := strings.Cut(after, " ")
+	return name
+}
+
+func (r *fakeRecorder) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn{recorder: r}, nil
+}
+
+func (r *fakeRecorder) Driver() driver.Driver {
+	return fakeDriver{recorder: r}
+}
+
+type fakeDriver struct {
+	recorder *fakeRecorder
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn(d), nil
+}
+
+type fakeConn struct {
+	recorder *fakeRecorder
+}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c fakeConn) ExecContext(
+	_ context.Context, query string, args []driver.NamedValue,
+) (driver.Result, error) {
+	_, err := c.recorder.record(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(0), nil
+}
+
+func (c fakeConn) QueryContext(
+	_ context.Context, query string, args []driver.NamedValue,
+) (driver.Rows, error) {
+	rows, err := c.recorder.record(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (*fakeRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (*fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -2,7 +2,7 @@ module github.com/erdnaxeli/migrator
 
 go 1.25.5
 
-// Dependency required for tests only
+// Dependency required for tests and the command-line tool only
 require modernc.org/sqlite v1.43.0
 
 require (
@@ -0,0 +1,184 @@
+package migrator_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+var errBackfill = errors.New("backfill failed")
+
+func backfillUp(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(
+		ctx, `UPDATE test_table SET description = 'Description of ' || name`,
+	)
+	return err
+}
+
+func backfillDown(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `UPDATE test_table SET description = NULL`)
+	return err
+}
+
+func TestGoMigration_OK(t *testing.T) {
+	// a Go migration is applied after the SQL ones, then rolled back
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(
+		db,
+		migrationsOKFS,
+		migrator.WithGoMigration(5, "backfill", backfillUp, backfillDown),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 5 {
+		t.Fatalf("expected version 5, got: %d", version)
+	}
+
+	var description sql.NullString
+	err = db.QueryRow(`SELECT description FROM test_table WHERE id = 1`).Scan(&description)
+	if err != nil {
+		t.Fatalf("failed to read description: %v", err)
+	}
+
+	if description.String != "Description of Test Name 1" {
+		t.Fatalf("unexpected description: %s", description.String)
+	}
+
+	err = m.Rollback(1)
+	if err != nil {
+		t.Fatalf("failed to roll back migration: %v", err)
+	}
+
+	err = db.QueryRow(`SELECT description FROM test_table WHERE id = 1`).Scan(&description)
+	if err != nil {
+		t.Fatalf("failed to read description: %v", err)
+	}
+
+	if description.Valid {
+		t.Fatalf("expected description to be NULL, got: %s", description.String)
+	}
+}
+
+func TestGoMigration_Error(t *testing.T) {
+	// a failing Go migration is rolled back
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(
+		db,
+		migrationsOKFS,
+		migrator.WithGoMigration(
+			5,
+			"backfill",
+			func(ctx context.Context, tx *sql.Tx) error {
+				err := backfillUp(ctx, tx)
+				if err != nil {
+					return err
+				}
+
+				return errBackfill
+			},
+			nil,
+		),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if !errors.Is(err, errBackfill) {
+		t.Fatalf("expected backfill error, got: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 4 {
+		t.Fatalf("expected version 4, got: %d", version)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM test_table WHERE description IS NOT NULL`).
+		Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+
+	if count != 0 {
+		t.Fatalf("expected no description to be set, got: %d", count)
+	}
+
+	err = m.Rollback(1)
+
+	var missingErr migrator.MissingDownMigrationError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("expected MissingDownMigrationError, got: %v", err)
+	}
+}
+
+func TestGoMigration_DuplicatedVersion(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	_, err := migrator.New(
+		db,
+		migrationsOKFS,
+		migrator.WithGoMigration(4, "backfill", backfillUp, backfillDown),
+	)
+
+	var dupErr migrator.DuplicateMigrationVersionError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected DuplicateMigrationVersionError, got: %v", err)
+	}
+
+	if dupErr.Version != 4 {
+		t.Fatalf("expected duplicated version '4', got: %d", dupErr.Version)
+	}
+}
+
+func TestGoMigration_MissingVersion(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	_, err := migrator.New(
+		db,
+		migrationsOKFS,
+		migrator.WithGoMigration(6, "backfill", backfillUp, backfillDown),
+	)
+
+	var missErr migrator.MissingMigrationVersionError
+	if !errors.As(err, &missErr) {
+		t.Fatalf("expected MissingMigrationVersionError, got: %v", err)
+	}
+
+	if missErr.Version != 5 {
+		t.Fatalf("expected missing version '5', got: %d", missErr.Version)
+	}
+}
@@ -0,0 +1,120 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// MigrationInfo describes the migration a hook is called for.
+type MigrationInfo struct {
+	Version int64
+	Name    string
+	// Down tells if the migration is rolled back.
+	Down bool
+	// Transactional tells if the migration is run in a transaction. If not, migration hooks
+	// are called with a nil transaction.
+	Transactional bool
+	// Baseline tells if the migration is a baseline or a squash migration applied on an empty
+	// database.
+	Baseline bool
+}
+
+// MigrationHook is called before or after a migration, in its transaction.
+//
+// If it returns an error, the migration is aborted and rolled back. Migrations run without
+// transaction cannot be rolled back: the after hook is called before the migration is recorded
+// or unrecorded, so that if it fails the history table is left unchanged, but the statements
+// already executed are kept.
+type MigrationHook func(ctx context.Context, tx *sql.Tx, info MigrationInfo) error
+
+// ErrorHook is called when a migration fails, after its transaction is rolled back.
+type ErrorHook func(ctx context.Context, info MigrationInfo, err error)
+
+// RunHook is called before or after a run of Migrate, MigrateTo or Rollback, while holding the
+// lock.
+//
+// If it returns an error, the run is aborted. Migrations already applied are not rolled back.
+type RunHook func(ctx context.Context) error
+
+type hooks struct {
+	beforeMigration []MigrationHook
+	afterMigration  []MigrationHook
+	onError         []ErrorHook
+	beforeRun       []RunHook
+	afterRun        []RunHook
+}
+
+// info returns the description of a migration given to hooks.
+func (migration Migration) info(down bool) MigrationInfo {
+	return MigrationInfo{
+		Version:       migration.version,
+		Name:          migration.name,
+		Down:          down,
+		Transactional: !migration.noTransaction,
+		Baseline:      migration.baseline || migration.squash,
+	}
+}
+
+// beforeMigration calls the hooks registered with WithBeforeMigration, stopping at the first
+// error.
+func (m *migrator) beforeMigration(ctx context.Context, tx *sql.Tx, info MigrationInfo) error {
+	for _, hook := range m.hooks.beforeMigration {
+		err := hook(ctx, tx, info)
+		if err != nil {
+			return fmt.Errorf("before hook failed for migration %d: %w", info.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// afterMigration calls the hooks registered with WithAfterMigration, stopping at the first
+// error.
+func (m *migrator) afterMigration(ctx context.Context, tx *sql.Tx, info MigrationInfo) error {
+	for _, hook := range m.hooks.afterMigration {
+		err := hook(ctx, tx, info)
+		if err != nil {
+			return fmt.Errorf("after hook failed for migration %d: %w", info.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// callErrorHooks calls the error hooks. They are given a context that is not canceled, so
+// that they can report an interrupted migration.
+func (m *migrator) callErrorHooks(ctx context.Context, info MigrationInfo, err error) {
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range m.hooks.onError {
+		hook(ctx, info, err)
+	}
+}
+
+// run calls f between the run hooks. Hooks are not called in dry run mode.
+func (m *migrator) run(ctx context.Context, f func() error) error {
+	if m.dryRun {
+		return f()
+	}
+
+	for _, hook := range m.hooks.beforeRun {
+		err := hook(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := f()
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range m.hooks.afterRun {
+		err = hook(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
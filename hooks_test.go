@@ -0,0 +1,211 @@
+package migrator_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+var errHook = errors.New("hook failed")
+
+// hookRecorder records the calls to hooks.
+type hookRecorder struct {
+	events []string
+	// failAfter makes the after migration hook fail for this version.
+	failAfter int64
+}
+
+func (r *hookRecorder) options() []migrator.Option {
+	return []migrator.Option{
+		migrator.WithBeforeRun(func(context.Context) error {
+			r.events = append(r.events, "before run")
+			return nil
+		}),
+		migrator.WithAfterRun(func(context.Context) error {
+			r.events = append(r.events, "after run")
+			return nil
+		}),
+		migrator.WithBeforeMigration(
+			func(_ context.Context, tx *sql.Tx, info migrator.MigrationInfo) error {
+				r.events = append(r.events, r.event("before", tx, info))
+				return nil
+			},
+		),
+		migrator.WithAfterMigration(
+			func(ctx context.Context, tx *sql.Tx, info migrator.MigrationInfo) error {
+				r.events = append(r.events, r.event("after", tx, info))
+
+				if info.Version == r.failAfter {
+					return errHook
+				}
+
+				if tx != nil && !info.Down {
+					_, err := tx.ExecContext(
+						ctx, `INSERT INTO audit (version) VALUES (?)`, info.Version,
+					)
+					return err
+				}
+
+				return nil
+			},
+		),
+		migrator.WithOnError(func(_ context.Context, info migrator.MigrationInfo, err error) {
+			r.events = append(r.events, fmt.Sprintf("error %d: %v", info.Version, err))
+		}),
+	}
+}
+
+func (r *hookRecorder) event(name string, tx *sql.Tx, info migrator.MigrationInfo) string {
+	if info.Down {
+		name += " down"
+	}
+
+	if tx == nil {
+		name += " without transaction"
+	}
+
+	return fmt.Sprintf("%s %d", name, info.Version)
+}
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t, `CREATE TABLE audit (version INTEGER)`)
+	defer db.Close()
+
+	var recorder hookRecorder
+	m, err := migrator.New(db, migrationsDownFS, recorder.options()...)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.MigrateTo(2)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	err = m.Rollback(1)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+
+	expected := []string{
+		"before run",
+		"before 1",
+		"after 1",
+		"before 2",
+		"after 2",
+		"after run",
+		"before run",
+		"before down 2",
+		"after down 2",
+		"after run",
+	}
+	if !slices.Equal(recorder.events, expected) {
+		t.Fatalf("expected events %q, got: %q", expected, recorder.events)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM audit`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count audit rows: %v", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected 2 audit rows, got: %d", count)
+	}
+}
+
+func TestHooks_Error(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t, `CREATE TABLE audit (version INTEGER)`)
+	defer db.Close()
+
+	recorder := hookRecorder{failAfter: 2}
+	m, err := migrator.New(db, migrationsDownFS, recorder.options()...)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if !errors.Is(err, errHook) {
+		t.Fatalf("expected the hook error, got: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 1 {
+		t.Fatalf("expected migration 2 to be rolled back, got version: %d", version)
+	}
+
+	expected := []string{
+		"before run",
+		"before 1",
+		"after 1",
+		"before 2",
+		"after 2",
+		"error 2: after hook failed for migration 2: hook failed",
+	}
+	if !slices.Equal(recorder.events, expected) {
+		t.Fatalf("expected events %q, got: %q", expected, recorder.events)
+	}
+}
+
+func TestHooks_NoTransaction(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t, `CREATE TABLE audit (version INTEGER)`)
+	defer db.Close()
+
+	var recorder hookRecorder
+	m, err := migrator.New(db, noTransactionFS, recorder.options()...)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	if !slices.Contains(recorder.events, "after without transaction 2") {
+		t.Fatalf("expected hooks without transaction, got: %q", recorder.events)
+	}
+}
+
+func TestHooks_NoTransactionError(t *testing.T) {
+	// a migration run without transaction is not recorded if its after hook fails
+	t.Parallel()
+
+	db := getDB(t, `CREATE TABLE audit (version INTEGER)`)
+	defer db.Close()
+
+	recorder := hookRecorder{failAfter: 2}
+	m, err := migrator.New(db, noTransactionFS, recorder.options()...)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if !errors.Is(err, errHook) {
+		t.Fatalf("expected the hook error, got: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 1 {
+		t.Fatalf("expected migration 2 to not be recorded, got version: %d", version)
+	}
+}
@@ -3,21 +3,59 @@ package migrator
 import (
 	"bufio"
 	"cmp"
+	"context"
 	"fmt"
+	"io"
 	"io/fs"
+	"path"
 	"slices"
 	"strings"
 )
 
-func loadMigrations(directory fs.FS) ([]Migration, error) {
-	matches, err := fs.Glob(directory, "*.sql")
+// Validate loads the migration files from the provided fs.FS and checks them, without
+// connecting to any database.
+//
+// It returns the same errors as New.
+func Validate(directory fs.FS, opts ...Option) error {
+	o := newOptions(opts)
+	_, err := loadMigrationSet(context.Background(), o.fsSource(directory), o)
+	return err
+}
+
+// LatestVersion returns the highest version of the migration files of the provided fs.FS, or 0
+// if there is none. Files are found like New does, in the subdirectories too with
+// WithRecursive, but are not read, so templates do not need their data.
+//
+// It returns an InvalidMigrationFilenameError if a file name does not match FilenameRgx.
+func LatestVersion(directory fs.FS, opts ...Option) (int64, error) {
+	o := newOptions(opts)
+	filenames, err := findMigrationFiles(directory, o.recursive)
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, filename := range filenames {
+		version, _, err := parseFilename(filename)
+		if err != nil {
+			return 0, err
+		}
+
+		latest = max(latest, version)
+	}
+
+	return latest, nil
+}
+
+func loadMigrations(directory fs.FS, recursive bool, data map[string]any) ([]Migration, error) {
+	filenames, err := findMigrationFiles(directory, recursive)
 	if err != nil {
 		return nil, err
 	}
 
 	var migrations []Migration
-	for _, filename := range matches {
-		migration, err := loadMigration(directory, filename)
+	for _, filename := range filenames {
+		migration, err := loadMigration(directory, filename, data)
 		if err != nil {
 			return nil, err
 		}
@@ -28,96 +66,236 @@ func loadMigrations(directory fs.FS) ([]Migration, error) {
 	return migrations, nil
 }
 
-func loadMigration(directory fs.FS, filename string) (Migration, error) {
-	submatches := FilenameRgx.FindSubmatch([]byte(filename))
-	if submatches == nil {
-		return Migration{}, InvalidMigrationFilenameError{Filename: filename}
+// findMigrationFiles returns the paths of the migration files, in the root directory only or
+// in the whole tree.
+func findMigrationFiles(directory fs.FS, recursive bool) ([]string, error) {
+	if !recursive {
+		matches, err := fs.Glob(directory, "*.sql")
+		if err != nil {
+			return nil, err
+		}
+
+		templates, err := fs.Glob(directory, "*.sql"+templateSuffix)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(matches, templates...), nil
 	}
 
-	versionStr := string(submatches[1])
-	name := string(submatches[2])
+	var filenames []string
+	err := fs.WalkDir(directory, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 
-	var version int
-	_, err := fmt.Sscanf(versionStr, "%d", &version)
+		if !d.IsDir() &&
+			(strings.HasSuffix(p, ".sql") || strings.HasSuffix(p, ".sql"+templateSuffix)) {
+			filenames = append(filenames, p)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return Migration{}, fmt.Errorf("error while parsing version: %s, %w", versionStr, err)
+		return nil, err
+	}
+
+	return filenames, nil
+}
+
+// loadMigration reads a migration file. The filename is its path in the directory.
+//
+// Files ending with ".tmpl" are rendered with the template data before being parsed.
+func loadMigration(directory fs.FS, filename string, data map[string]any) (Migration, error) {
+	file, err := directory.Open(filename)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	defer func() { _ = file.Close() }()
+
+	var r io.Reader = file
+	if strings.HasSuffix(filename, templateSuffix) {
+		r, err = renderTemplate(filename, file, data)
+		if err != nil {
+			return Migration{}, err
+		}
 	}
 
-	upSQL, err := readMigrationSQL(directory, filename)
+	return ParseMigration(filename, r)
+}
+
+// ParseMigration parses a migration written like a migration file, for Sources reading them
+// from something else than an fs.FS, like a database table.
+//
+// The version and the name are read from the filename, only its base name must match
+// FilenameRgx. Templates are not rendered.
+func ParseMigration(filename string, r io.Reader) (Migration, error) {
+	version, name, err := parseFilename(filename)
 	if err != nil {
 		return Migration{}, err
 	}
 
-	migration := Migration{
-		version: version,
-		name:    name,
-		upSQL:   upSQL,
+	migration, err := readMigrationSQL(filename, r)
+	if err != nil {
+		return Migration{}, err
 	}
+
+	migration.version = version
+	migration.name = name
+	migration.filename = filename
+	migration.checksum = computeChecksum(migration.upSQL)
 	return migration, nil
 }
 
-func readMigrationSQL(directory fs.FS, filename string) ([]string, error) {
-	file, err := directory.Open(filename)
+// parseFilename returns the version and the name of a migration file.
+func parseFilename(filename string) (int64, string, error) {
+	basename := strings.TrimSuffix(path.Base(filename), templateSuffix)
+	submatches := FilenameRgx.FindSubmatch([]byte(basename))
+	if submatches == nil {
+		return 0, "", InvalidMigrationFilenameError{Filename: filename}
+	}
+
+	versionStr := string(submatches[1])
+
+	var version int64
+	_, err := fmt.Sscanf(versionStr, "%d", &version)
 	if err != nil {
-		return nil, err
+		return 0, "", fmt.Errorf("error while parsing version: %s, %w", versionStr, err)
 	}
 
-	defer func() { _ = file.Close() }()
-	scanner := bufio.NewScanner(file)
+	return version, string(submatches[2]), nil
+}
+
+// readMigrationSQL reads the up and down statements of a migration file.
+//
+// The file must start with a "-- +migrate Up" line. It can contain a "-- +migrate Down"
+// line, after which statements are part of the down migration.
+// Lines between "-- +migrate StatementBegin" and "-- +migrate StatementEnd" are a single
+// statement. A "-- +migrate NoTransaction" line disables the transaction for the whole file.
+// A "-- +migrate Baseline" line marks the file as a baseline, and a "-- +migrate Squash" line
+// as a squash migration.
+func readMigrationSQL(filename string, r io.Reader) (Migration, error) {
+	scanner := bufio.NewScanner(r)
 
 	if !scanner.Scan() {
-		return nil, EmptyMigrationError{Filename: filename}
+		return Migration{}, EmptyMigrationError{Filename: filename}
 	}
 
 	if scanner.Text() != "-- +migrate Up" {
-		return nil, InvalidMigrationFileError{Filename: filename}
+		return Migration{}, InvalidMigrationFileError{
+			Filename: filename,
+			Line:     1,
+			Reason:   `first line must be "-- +migrate Up"`,
+		}
 	}
 
-	var b strings.Builder
-	var upSQL []string
+	p := migrationParser{filename: filename, line: 1}
+	p.section = &p.migration.upSQL
 
 	for scanner.Scan() {
-		bytes := scanner.Bytes()
-		b.Write(bytes)
+		p.line++
 
-		if len(bytes) > 0 && bytes[len(bytes)-1] == ';' {
-			upSQL = append(upSQL, b.String())
-			b.Reset()
-		} else {
-			b.WriteString("\n")
+		err := p.parseLine(scanner.Text())
+		if err != nil {
+			return Migration{}, err
 		}
 	}
 
-	if b.Len() > 0 {
-		upSQL = append(upSQL, b.String())
+	if err := scanner.Err(); err != nil {
+		return Migration{}, err
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if p.blockLine != 0 {
+		return Migration{}, UnbalancedStatementMarkerError{Filename: filename, Line: p.blockLine}
 	}
 
-	return upSQL, nil
+	p.flush()
+	return p.migration, nil
 }
 
-func validateMigrations(migrations []Migration) (int, error) {
-	seenVersions := make(map[int]bool)
-	maxVersion := 0
-	for _, m := range migrations {
-		if seenVersions[m.version] {
-			return 0, DuplicateMigrationVersionError{Version: m.version}
+// migrationParser parses the lines of a migration file after the "-- +migrate Up" one.
+type migrationParser struct {
+	filename  string
+	line      int
+	migration Migration
+
+	// section is the list of statements being read, up or down.
+	section *[]string
+	b       strings.Builder
+	// blockLine is the line of the current StatementBegin marker, or 0.
+	blockLine int
+}
+
+func (p *migrationParser) parseLine(text string) error {
+	switch text {
+	case "-- +migrate Down":
+		if p.blockLine != 0 {
+			return UnbalancedStatementMarkerError{Filename: p.filename, Line: p.blockLine}
 		}
 
-		if m.version > maxVersion {
-			maxVersion = m.version
+		if p.migration.hasDown {
+			return InvalidMigrationFileError{
+				Filename: p.filename,
+				Line:     p.line,
+				Reason:   `duplicate "-- +migrate Down" line`,
+			}
+		}
+
+		p.flush()
+		p.section = &p.migration.downSQL
+		p.migration.hasDown = true
+	case "-- +migrate NoTransaction":
+		p.migration.noTransaction = true
+	case "-- +migrate Baseline":
+		p.migration.baseline = true
+	case "-- +migrate Squash":
+		p.migration.squash = true
+	case "-- +migrate StatementBegin":
+		if p.blockLine != 0 {
+			return UnbalancedStatementMarkerError{Filename: p.filename, Line: p.line}
+		}
+
+		p.flush()
+		p.blockLine = p.line
+	case "-- +migrate StatementEnd":
+		if p.blockLine == 0 {
+			return UnbalancedStatementMarkerError{Filename: p.filename, Line: p.line}
 		}
 
-		seenVersions[m.version] = true
+		if statement := strings.TrimSpace(p.b.String()); statement != "" {
+			*p.section = append(*p.section, statement)
+		}
+
+		p.b.Reset()
+		p.blockLine = 0
+	default:
+		p.b.WriteString(text)
+		p.b.WriteString("\n")
 	}
 
-	for v := 1; v <= maxVersion; v++ {
-		if !seenVersions[v] {
-			return 0, MissingMigrationVersionError{Version: v}
+	return nil
+}
+
+// flush splits the SQL read so far into statements of the current section.
+func (p *migrationParser) flush() {
+	*p.section = append(*p.section, splitStatements(p.b.String())...)
+	p.b.Reset()
+}
+
+func validateMigrations(migrations []Migration, sparse bool) (int64, error) {
+	seenVersions := make(map[int64]Migration)
+	var maxVersion int64
+	for _, m := range migrations {
+		if seen, ok := seenVersions[m.version]; ok {
+			return 0, newDuplicateMigrationVersionError(seen, m)
 		}
+
+		if m.version > maxVersion {
+			maxVersion = m.version
+		}
+
+		seenVersions[m.version] = m
 	}
 
 	slices.SortFunc(
@@ -125,5 +303,46 @@ func validateMigrations(migrations []Migration) (int, error) {
 		func(a Migration, b Migration) int { return cmp.Compare(a.version, b.version) },
 	)
 
+	if sparse {
+		return maxVersion, nil
+	}
+
+	// the migrations replaced by a squash migration can be deleted
+	expected := int64(1)
+	for _, m := range migrations {
+		if m.squash {
+			expected = max(expected, m.version+1)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version < expected {
+			continue
+		}
+
+		if m.version != expected {
+			return 0, MissingMigrationVersionError{Version: expected}
+		}
+
+		expected++
+	}
+
 	return maxVersion, nil
 }
+
+func newDuplicateMigrationVersionError(a Migration, b Migration) DuplicateMigrationVersionError {
+	return DuplicateMigrationVersionError{
+		Version:   a.version,
+		Filenames: [2]string{a.path(), b.path()},
+	}
+}
+
+// path returns the path of the migration file, prefixed by the name of its source if it is
+// not the fs.FS given to New.
+func (migration Migration) path() string {
+	if migration.source == "" {
+		return migration.filename
+	}
+
+	return migration.source + ":" + migration.filename
+}
@@ -0,0 +1,274 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errLockNotAcquired = errors.New("lock not acquired")
+
+// Locker prevents several processes from migrating the same database at the same time.
+type Locker interface {
+	// Lock blocks until the lock is acquired or the context is done.
+	//
+	// It returns a function releasing the lock.
+	Lock(ctx context.Context, db *sql.DB) (func(context.Context) error, error)
+}
+
+// withLock runs f while holding the migration lock.
+//
+// The applied migrations are read again once the lock is acquired, as another process may
+// have migrated the database in the meantime, and their checksums are verified.
+func (m *migrator) withLock(ctx context.Context, f func() error) error {
+	return m.lock(ctx, func() error {
+		err := m.refresh(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = m.verifyChecksums(ctx)
+		if err != nil {
+			return err
+		}
+
+		return f()
+	})
+}
+
+// lock runs f while holding the migration lock.
+//
+// If the lock is already held, like when the history table is created while migrating, f is
+// run directly. In dry run mode, nothing is written to the database, so the lock is not
+// acquired either.
+func (m *migrator) lock(ctx context.Context, f func() error) (err error) {
+	if m.locked || m.dryRun {
+		return f()
+	}
+
+	unlock, err := m.locker.Lock(ctx, m.db)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	m.locked = true
+	defer func() { m.locked = false }()
+
+	defer func() {
+		// the lock must be released even if the context is canceled
+		unlockErr := unlock(context.WithoutCancel(ctx))
+		if unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release migration lock: %w", unlockErr))
+		}
+	}()
+
+	return f()
+}
+
+type noopLocker struct{}
+
+func (noopLocker) Lock(context.Context, *sql.DB) (func(context.Context) error, error) {
+	return func(context.Context) error { return nil }, nil
+}
+
+// PostgresLocker is a Locker using PostgreSQL session advisory locks.
+//
+// The lock is held by a dedicated connection taken from the pool.
+type PostgresLocker struct {
+	// Key identifies the advisory lock.
+	Key int64
+}
+
+// Lock implements Locker.
+func (l PostgresLocker) Lock(ctx context.Context, db *sql.DB) (func(context.Context) error, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, l.Key)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	unlock := func(ctx context.Context) error {
+		defer func() { _ = conn.Close() }()
+
+		_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.Key)
+		return err
+	}
+	return unlock, nil
+}
+
+// MySQLLocker is a Locker using MySQL named locks with GET_LOCK().
+//
+// The lock is held by a dedicated connection taken from the pool.
+type MySQLLocker struct {
+	// Name identifies the lock. It defaults to "migrator".
+	Name string
+}
+
+// Lock implements Locker.
+func (l MySQLLocker) Lock(ctx context.Context, db *sql.DB) (func(context.Context) error, error) {
+	name := l.Name
+	if name == "" {
+		name = "migrator"
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// a negative timeout means waiting forever, the wait is interrupted by the context
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, -1)`, name).Scan(&acquired)
+	if err == nil && acquired.Int64 != 1 {
+		err = errLockNotAcquired
+	}
+
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	unlock := func(ctx context.Context) error {
+		defer func() { _ = conn.Close() }()
+
+		_, err := conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, name)
+		return err
+	}
+	return unlock, nil
+}
+
+// TableLocker is a Locker using a row in a dedicated table.
+//
+// It works with any database, but if a process dies while holding the lock, the row must be
+// deleted by hand.
+type TableLocker struct {
+	// Table is the name of the lock table. It defaults to "schema_migrations_lock".
+	Table string
+	// Schema is the schema of the lock table. It defaults to the one given to WithSchema.
+	Schema string
+	// Dialect is used to quote the name of the lock table. It defaults to the one given to
+	// WithDialect.
+	Dialect Dialect
+	// RetryInterval is the time to wait between two attempts. It defaults to one second.
+	RetryInterval time.Duration
+}
+
+// Lock implements Locker.
+func (l TableLocker) Lock(ctx context.Context, db *sql.DB) (func(context.Context) error, error) {
+	name := l.Table
+	if name == "" {
+		name = "schema_migrations_lock"
+	}
+
+	dialect := l.Dialect
+	if dialect == nil {
+		dialect = SQLite
+	}
+
+	table := qualifiedTableName(dialect, l.Schema, name)
+
+	retryInterval := l.RetryInterval
+	if retryInterval == 0 {
+		retryInterval = time.Second
+	}
+
+	err := createTableIfNotExists(
+		ctx, db, table, fmt.Sprintf("CREATE TABLE %s (id INTEGER PRIMARY KEY)", table),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	retried := false
+	for {
+		// the insert fails on the primary key while another process holds the lock
+		_, err = db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (id) VALUES (1)", table))
+		if err == nil {
+			break
+		}
+
+		// if the lock row does not exist, the insert failed for another reason, unless the
+		// lock was released in the meantime: the insert is then tried again once
+		held, checkErr := isTableLockHeld(ctx, db, table)
+		if checkErr != nil || (!held && retried) {
+			return nil, err
+		}
+
+		if !held {
+			retried = true
+			continue
+		}
+
+		retried = false
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+
+	unlock := func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = 1", table))
+		return err
+	}
+	return unlock, nil
+}
+
+// withHistoryTable returns the locker with the dialect and the schema of the history table, if
+// it is a TableLocker without them.
+func withHistoryTable(locker Locker, dialect Dialect, schema string) Locker {
+	l, ok := locker.(TableLocker)
+	if !ok {
+		return locker
+	}
+
+	if l.Dialect == nil {
+		l.Dialect = dialect
+	}
+
+	if l.Schema == "" {
+		l.Schema = schema
+	}
+
+	return l
+}
+
+// isTableLockHeld tells if the row of a TableLocker exists.
+func isTableLockHeld(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var id int
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT id FROM %s WHERE id = 1", table)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// createTableIfNotExists creates a table if it does not exist yet.
+//
+// If another process creates the table concurrently, it does not fail.
+func createTableIfNotExists(ctx context.Context, db *sql.DB, table string, ddl string) error {
+	_, err := db.ExecContext(ctx, "SELECT 1 FROM "+table)
+	if err == nil {
+		return nil
+	}
+
+	_, err = db.ExecContext(ctx, ddl)
+	if err != nil {
+		if _, checkErr := db.ExecContext(ctx, "SELECT 1 FROM "+table); checkErr == nil {
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package migrator_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+func TestTableLocker(t *testing.T) {
+	// the lock cannot be acquired twice
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	locker := migrator.TableLocker{RetryInterval: 10 * time.Millisecond}
+
+	unlock, err := locker.Lock(context.Background(), db)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = locker.Lock(ctx, db)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected lock to time out, got: %v", err)
+	}
+
+	err = unlock(context.Background())
+	if err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+
+	unlock, err = locker.Lock(context.Background(), db)
+	if err != nil {
+		t.Fatalf("failed to acquire lock after release: %v", err)
+	}
+
+	err = unlock(context.Background())
+	if err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+}
+
+func TestTableLocker_InsertError(t *testing.T) {
+	// an insert failing for another reason than the lock being held is not retried
+	t.Parallel()
+
+	db := getDB(
+		t, "CREATE TABLE schema_migrations_lock (id INTEGER PRIMARY KEY, owner TEXT NOT NULL)",
+	)
+	defer db.Close()
+
+	locker := migrator.TableLocker{RetryInterval: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := locker.Lock(ctx, db)
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the insert error, got: %v", err)
+	}
+}
+
+func TestMigrate_Concurrent(t *testing.T) {
+	// several migrators apply the same migrations at the same time
+	t.Parallel()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "db.sqlite") + "?_pragma=busy_timeout(5000)"
+	locker := migrator.TableLocker{RetryInterval: 10 * time.Millisecond}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Go(func() {
+			db, err := sql.Open("sqlite", dsn)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer db.Close()
+
+			m, err := migrator.New(db, migrationsOKFS, migrator.WithLocker(locker))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			errs[i] = m.Migrate()
+		})
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("migrator %d failed: %v", i, err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM test_table`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows in test_table: %v", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected two rows in test_table, got: %d", count)
+	}
+}
+
+func TestTableLocker_Quoted(t *testing.T) {
+	// the lock table is quoted like the history table
+	t.Parallel()
+
+	db, recorder := newFakeDB()
+	defer db.Close()
+
+	m, err := migrator.New(
+		db,
+		migrationsOKFS,
+		migrator.WithDialect(migrator.MySQL),
+		migrator.WithLocker(migrator.TableLocker{}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	expected := []string{
+		"CREATE TABLE `schema_migrations_lock` (id INTEGER PRIMARY KEY)",
+		"INSERT INTO `schema_migrations_lock` (id) VALUES (1)",
+		"DELETE FROM `schema_migrations_lock` WHERE id = 1",
+	}
+	for _, statement := range expected {
+		if !slices.Contains(recorder.Statements(), statement) {
+			t.Errorf("expected statement %q, got: %q", statement, recorder.Statements())
+		}
+	}
+}
+
+func TestPostgresLocker(t *testing.T) {
+	t.Parallel()
+
+	db, recorder := newFakeDB()
+	defer db.Close()
+
+	unlock, err := migrator.PostgresLocker{Key: 42}.Lock(context.Background(), db)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	err = unlock(context.Background())
+	if err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+
+	expected := []string{`SELECT pg_advisory_lock($1)`, `SELECT pg_advisory_unlock($1)`}
+	if !slices.Equal(recorder.Statements(), expected) {
+		t.Fatalf("expected statements %q, got: %q", expected, recorder.Statements())
+	}
+}
+
+func TestMySQLLocker(t *testing.T) {
+	t.Parallel()
+
+	db, recorder := newFakeDB()
+	defer db.Close()
+
+	recorder.SetResult(int64(1))
+
+	unlock, err := migrator.MySQLLocker{}.Lock(context.Background(), db)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	err = unlock(context.Background())
+	if err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+
+	expected := []string{`SELECT GET_LOCK(?, -1)`, `SELECT RELEASE_LOCK(?)`}
+	if !slices.Equal(recorder.Statements(), expected) {
+		t.Fatalf("expected statements %q, got: %q", expected, recorder.Statements())
+	}
+}
+
+func TestMySQLLocker_NotAcquired(t *testing.T) {
+	t.Parallel()
+
+	db, recorder := newFakeDB()
+	defer db.Close()
+
+	recorder.SetResult(int64(0))
+
+	_, err := migrator.MySQLLocker{}.Lock(context.Background(), db)
+	if err == nil {
+		t.Fatalf("expected lock to not be acquired, got no error")
+	}
+}
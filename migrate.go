@@ -1,66 +1,291 @@
 package migrator
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
+	"time"
 )
 
 func (m *migrator) Migrate() error {
+	return m.MigrateContext(context.Background())
+}
+
+func (m *migrator) MigrateContext(ctx context.Context) error {
 	if len(m.migrations) == 0 {
-		log.Print("No migrations to apply.")
+		m.logger.Info("no migrations to apply")
 		return nil
 	}
 
-	if m.currentVersion == m.lastVersion {
-		log.Print("Database is already up to date.")
+	return m.withLock(ctx, func() error {
+		return m.run(ctx, func() error {
+			return m.migrate(ctx)
+		})
+	})
+}
+
+func (m *migrator) migrate(ctx context.Context) error {
+	if len(m.pending(m.lastVersion)) == 0 {
+		m.logger.Info("database is already up to date", "version", m.currentVersion)
 		return nil
 	}
 
-	for i := m.currentVersion + 1; i <= m.lastVersion; i++ {
-		err := m.applyMigration(i)
+	start := time.Now()
+	err := m.migrateUp(ctx, m.lastVersion)
+	if err != nil {
+		return err
+	}
+
+	if m.dryRun {
+		m.logger.Info("dry run completed, no migration applied")
+		return nil
+	}
+
+	m.logger.Info(
+		"all migrations applied successfully",
+		"version", m.currentVersion,
+		"duration", time.Since(start),
+	)
+
+	return nil
+}
+
+func (m *migrator) MigrateTo(version int64) error {
+	return m.MigrateToContext(context.Background(), version)
+}
+
+func (m *migrator) MigrateToContext(ctx context.Context, version int64) error {
+	if version < 0 || version > m.lastVersion {
+		return InvalidTargetVersionError{Version: version}
+	}
+
+	return m.withLock(ctx, func() error {
+		return m.run(ctx, func() error {
+			err := m.migrateDown(ctx, version)
+			if err != nil {
+				return err
+			}
+
+			return m.migrateUp(ctx, version)
+		})
+	})
+}
+
+func (m *migrator) migrateUp(ctx context.Context, version int64) error {
+	migrations := m.pending(version)
+
+	baseline, hasBaseline := m.baselineFor(version)
+	if hasBaseline {
+		migrations = above(migrations, baseline.version)
+	}
+
+	// on an empty database, a version below a squash migration cannot be reached once the files
+	// of the squashed migrations are deleted
+	if !hasBaseline && !m.sparse && m.currentVersion == 0 && int64(len(migrations)) != version {
+		return InvalidTargetVersionError{Version: version}
+	}
+
+	err := m.checkPending(migrations)
+	if err != nil {
+		return err
+	}
+
+	if hasBaseline {
+		applied, err := m.migrateBaseline(ctx, baseline)
 		if err != nil {
 			return err
 		}
+
+		if !applied {
+			// another process applied migrations in the meantime
+			err = m.refresh(ctx)
+			if err != nil {
+				return err
+			}
+
+			return m.migrateUp(ctx, version)
+		}
 	}
 
-	log.Print("All migrations applied successfully.")
+	for _, migration := range migrations {
+		if m.dryRun {
+			m.logDryRun("dry run: would apply migration", migration, migration.upSQL)
+			continue
+		}
+
+		start := time.Now()
+
+		applied, err := m.applyMigration(ctx, migration)
+		if err != nil {
+			m.logger.Error(
+				"failed to apply migration",
+				"version", migration.version,
+				"name", migration.name,
+				"duration", time.Since(start),
+				"error", err,
+			)
+
+			m.callErrorHooks(ctx, migration.info(false), err)
+			return wrapInterrupted(ctx, migration.version, err)
+		}
+
+		m.markApplied(migration.version)
+
+		if !applied {
+			m.logger.Info(
+				"migration already applied, skipping it",
+				"version", migration.version,
+				"name", migration.name,
+			)
+			continue
+		}
+
+		m.logger.Info(
+			"migration applied",
+			"version", migration.version,
+			"name", migration.name,
+			"duration", time.Since(start),
+		)
+	}
 
 	return nil
 }
 
-func (m *migrator) applyMigration(version int) error {
-	migration := m.migrations[version-1]
-	log.Printf("Applying migration %d: %s.", migration.version, migration.name)
+// applyMigration applies a migration in a transaction.
+//
+// It returns false if the migration was already applied by another process.
+func (m *migrator) applyMigration(ctx context.Context, migration Migration) (bool, error) {
+	m.logger.Info("applying migration", "version", migration.version, "name", migration.name)
+
+	if migration.noTransaction {
+		return m.applyWithoutTransaction(ctx, migration)
+	}
 
-	tx, err := m.db.Begin()
+	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf(
+		return false, fmt.Errorf(
 			"failed to begin transaction for migration %d: %w", migration.version, err,
 		)
 	}
 
 	defer func() { _ = tx.Rollback() }()
 
+	applied, err := m.isApplied(ctx, tx, migration.version)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %d: %w", migration.version, err)
+	}
+
+	if applied {
+		return false, nil
+	}
+
+	err = m.beforeMigration(ctx, tx, migration.info(false))
+	if err != nil {
+		return false, err
+	}
+
 	for _, sql := range migration.upSQL {
-		_, err = tx.Exec(sql)
+		_, err = tx.ExecContext(ctx, sql)
 		if err != nil {
-			return fmt.Errorf("failed to apply migration %d: %w", migration.version, err)
+			return false, fmt.Errorf("failed to apply migration %d: %w", migration.version, err)
 		}
 	}
 
-	_, err = tx.Exec(
-		`INSERT INTO schema_migrations (version) VALUES (?)`,
-		migration.version,
-	)
+	if migration.upFunc != nil {
+		err = migration.upFunc(ctx, tx)
+		if err != nil {
+			return false, fmt.Errorf("failed to apply migration %d: %w", migration.version, err)
+		}
+	}
+
+	err = m.recordMigration(ctx, tx, migration)
+	if err != nil {
+		return false, err
+	}
+
+	err = m.afterMigration(ctx, tx, migration.info(false))
 	if err != nil {
-		return fmt.Errorf("failed to record migration %d: %w", migration.version, err)
+		return false, err
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("failed to commit migration %d: %w", migration.version, err)
+		return false, fmt.Errorf("failed to commit migration %d: %w", migration.version, err)
+	}
+
+	return true, nil
+}
+
+// applyWithoutTransaction applies a migration outside of any transaction, on a single
+// connection.
+//
+// The migration is recorded only if all its statements succeed. If one fails, the previous
+// ones are not rolled back.
+func (m *migrator) applyWithoutTransaction(
+	ctx context.Context, migration Migration,
+) (bool, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to get a connection for migration %d: %w", migration.version, err,
+		)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	applied, err := m.isApplied(ctx, conn, migration.version)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %d: %w", migration.version, err)
+	}
+
+	if applied {
+		return false, nil
+	}
+
+	err = m.beforeMigration(ctx, nil, migration.info(false))
+	if err != nil {
+		return false, err
+	}
+
+	err = execWithoutTransaction(ctx, conn, migration.version, migration.upSQL)
+	if err != nil {
+		return false, err
+	}
+
+	// the migration is not recorded if the after hook fails, like if a statement failed
+	err = m.afterMigration(ctx, nil, migration.info(false))
+	if err != nil {
+		return false, err
+	}
+
+	err = m.recordMigration(ctx, conn, migration)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// execWithoutTransaction executes the statements of a migration, stopping at the first
+// error.
+func execWithoutTransaction(
+	ctx context.Context, conn *sql.Conn, version int64, statements []string,
+) error {
+	for i, sql := range statements {
+		_, err := conn.ExecContext(ctx, sql)
+		if err != nil {
+			return NonTransactionalMigrationError{Version: version, Statement: i + 1, Err: err}
+		}
 	}
 
-	m.currentVersion = migration.version
 	return nil
 }
+
+// wrapInterrupted returns a MigrationInterruptedError if the context is done, else err.
+func wrapInterrupted(ctx context.Context, version int64, err error) error {
+	if ctx.Err() != nil {
+		return MigrationInterruptedError{Version: version, Err: ctx.Err()}
+	}
+
+	return err
+}
@@ -1,10 +1,17 @@
 package migrator_test
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"embed"
+	"encoding/json"
+	"errors"
 	"io/fs"
+	"log/slog"
 	"testing"
+
+	"github.com/erdnaxeli/migrator"
 )
 
 //go:embed test_data/migration_rollback/*.sql
@@ -206,3 +213,166 @@ func TestMigrate_FromState(t *testing.T) {
 		t.Fatalf("expected another_test_table to exist, got error: %v", err)
 	}
 }
+
+// cancelHandler is a slog.Handler canceling a context when a message is logged for the n-th time.
+type cancelHandler struct {
+	msg    string
+	n      int
+	cancel context.CancelFunc
+	count  *int
+}
+
+func (cancelHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h cancelHandler) Handle(_ context.Context, record slog.Record) error {
+	if record.Message == h.msg {
+		*h.count++
+		if *h.count == h.n {
+			h.cancel()
+		}
+	}
+
+	return nil
+}
+
+func (h cancelHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h cancelHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func TestMigrateContext_Canceled(t *testing.T) {
+	// context is canceled while applying the third migration
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := slog.New(cancelHandler{
+		msg:    "applying migration",
+		n:      3,
+		cancel: cancel,
+		count:  new(int),
+	})
+
+	m, err := migrator.New(db, migrationsOKFS, migrator.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.MigrateContext(ctx)
+
+	var interruptedErr migrator.MigrationInterruptedError
+	if !errors.As(err, &interruptedErr) {
+		t.Fatalf("expected MigrationInterruptedError, got: %v", err)
+	}
+
+	if interruptedErr.Version != 3 {
+		t.Fatalf("expected interrupted version 3, got: %d", interruptedErr.Version)
+	}
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected error to wrap context.Canceled, got: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 2 {
+		t.Fatalf("expected version 2, got: %d", version)
+	}
+
+	// check that another_test_table does not exist
+	_, err = db.Exec(`SELECT id, name FROM another_test_table`)
+	if err == nil {
+		t.Fatalf("expected another_test_table to not exist, but it does")
+	}
+}
+
+func TestMigrate_Logger(t *testing.T) {
+	// each applied migration is logged with its attributes
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	m, err := migrator.New(db, migrationsOKFS, migrator.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	var applied []map[string]any
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record map[string]any
+		err = decoder.Decode(&record)
+		if err != nil {
+			t.Fatalf("failed to decode log record: %v", err)
+		}
+
+		if record["msg"] == "migration applied" {
+			applied = append(applied, record)
+		}
+	}
+
+	if len(applied) != 4 {
+		t.Fatalf("expected 4 applied migration records, got: %d", len(applied))
+	}
+
+	record := applied[1]
+	if record["version"] != float64(2) || record["name"] != "change_table" {
+		t.Fatalf("unexpected record attributes: %v", record)
+	}
+
+	if _, ok := record["duration"]; !ok {
+		t.Fatalf("expected record to have a duration, got: %v", record)
+	}
+}
+
+func TestMigrate_ConcurrentlyMigrated(t *testing.T) {
+	// another migrator applies the migrations between New and Migrate
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	first := getMigrator(t, db, migrationsOKFS)
+	second := getMigrator(t, db, migrationsOKFS)
+
+	err := first.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations with the first migrator: %v", err)
+	}
+
+	err = second.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations with the second migrator: %v", err)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM test_table`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows in test_table: %v", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected two rows in test_table, got: %d", count)
+	}
+}
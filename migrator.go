@@ -2,9 +2,13 @@
 package migrator
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"io/fs"
+	"log/slog"
 	"regexp"
+	"slices"
 )
 
 // FilenameRgx is the regular expression to match migration filenames.
@@ -13,62 +17,415 @@ var FilenameRgx = regexp.MustCompile(`^(\d+)_(.*)\.sql$`)
 // Migrator is the interface to manage database migrations.
 type Migrator interface {
 	// Migrate applies all pending database migrations.
+	//
+	// It returns a ChecksumMismatchError if the file of an applied migration was modified,
+	// an OutOfOrderMigrationError if a migration older than the latest applied one is pending
+	// and it is not allowed, and a SquashedMigrationError if a squash migration is pending on
+	// a database that is not empty.
 	Migrate() error
 
+	// MigrateContext is like Migrate but uses the given context.
+	//
+	// If the context is canceled, the migration in progress is rolled back and a
+	// MigrationInterruptedError is returned.
+	MigrateContext(ctx context.Context) error
+
+	// MigrateTo applies or rolls back migrations until the database reaches the given version.
+	//
+	// It returns an InvalidTargetVersionError if the version is above the latest migration, or
+	// if it is below a squash migration on an empty database.
+	MigrateTo(version int64) error
+
+	// MigrateToContext is like MigrateTo but uses the given context.
+	MigrateToContext(ctx context.Context, version int64) error
+
+	// Rollback rolls back the given number of applied migrations.
+	//
+	// It returns an InvalidRollbackStepsError if fewer migrations are applied.
+	Rollback(steps int) error
+
+	// RollbackContext is like Rollback but uses the given context.
+	RollbackContext(ctx context.Context, steps int) error
+
+	// Plan returns the migrations Migrate would apply, without applying them.
+	Plan() ([]PlannedMigration, error)
+
+	// PlanContext is like Plan but uses the given context.
+	PlanContext(ctx context.Context) ([]PlannedMigration, error)
+
+	// Status returns the state of every known migration, and of the applied migrations that
+	// are unknown, ordered by version.
+	Status() ([]MigrationStatus, error)
+
+	// StatusContext is like Status but uses the given context.
+	StatusContext(ctx context.Context) ([]MigrationStatus, error)
+
+	// Repair updates the checksums recorded for the applied migrations, to accept the changes
+	// made to their files. The migrator must be created with WithoutChecksumVerification, else
+	// New fails on the modified files.
+	Repair() error
+
+	// RepairContext is like Repair but uses the given context.
+	RepairContext(ctx context.Context) error
+
+	// Baseline records the migrations up to the given version as applied, without executing
+	// them. It is used to adopt migrator on a database whose schema already exists.
+	Baseline(version int64) error
+
+	// BaselineContext is like Baseline but uses the given context.
+	BaselineContext(ctx context.Context, version int64) error
+
 	// Version returns the current version of the database schema.
-	Version() (int, error)
+	Version() (int64, error)
+
+	// VersionContext is like Version but uses the given context.
+	VersionContext(ctx context.Context) (int64, error)
 }
 
 type migrator struct {
-	db *sql.DB
+	db      *sql.DB
+	logger  *slog.Logger
+	dialect Dialect
+	locker  Locker
+	// locked tells if the lock is held.
+	locked bool
+	dryRun bool
+	// table is the quoted and qualified name of the history table.
+	table     string
+	tableName string
+	schema    string
+	namespace string
+	// historyMissing tells that the history table does not exist, in dry run mode only.
+	historyMissing bool
+	// historyReady tells that the history table has the current layout, so it is not checked
+	// again.
+	historyReady bool
+	// sparse tells if versions can have gaps, see WithSparseVersions.
+	sparse     bool
+	outOfOrder bool
+	hooks      hooks
 
 	migrations     []Migration
-	currentVersion int
-	lastVersion    int
+	baselines      []Migration
+	currentVersion int64
+	lastVersion    int64
+	// applied is the set of applied versions. It is only used with sparse versions, else every
+	// version up to currentVersion is applied.
+	applied map[int64]bool
 }
 
 // Migration represents a database migration.
+//
+// Migrations are loaded by a Source, or created with ParseMigration and NewGoMigration.
 type Migration struct {
-	version int
+	version int64
 	name    string
 	upSQL   []string
+	downSQL []string
+	hasDown bool
+	// noTransaction tells if the migration must be run outside of a transaction.
+	noTransaction bool
+	// checksum is computed from upSQL. It is empty for Go migrations.
+	checksum string
+	// baseline tells if the migration is a snapshot of the schema at its version, applied
+	// instead of the previous migrations on empty databases.
+	baseline bool
+	// squash tells if the migration replaces all the migrations up to its version, which can
+	// be deleted. It is applied like a baseline, and is a regular migration otherwise.
+	squash bool
+	// filename is the path of the migration file. It is empty for Go migrations.
+	filename string
+	// source is the name of the fs.FS containing the file, empty for the one given to New.
+	source string
+
+	upFunc   GoMigrationFunc
+	downFunc GoMigrationFunc
 }
 
+// GoMigrationFunc is a migration written in Go.
+//
+// It is run in the migration transaction.
+type GoMigrationFunc func(ctx context.Context, tx *sql.Tx) error
+
 // New creates a new Migrator instance.
 //
-// It loads migrations from the provided fs.FS and checks the current database version.
-// If the schema_migrations table does not exist, it creates it.
+// It loads migrations from the provided fs.FS, merges them with the Go migrations given as
+// options, checks the current database version and the checksums of the applied migrations.
+// Files named like "1_name.sql.tmpl" are rendered as text/template templates with the data
+// given by WithTemplateData.
+// If the history table does not exist, it creates it.
+// Its behavior can be customized with options. To load the migrations from something else
+// than an fs.FS, see NewFromSource.
 //
 // It can returns the following errors:
 //   - InvalidMigrationFilenameError
 //   - InvalidMigrationFileError
 //   - EmptyMigrationError
+//   - UnbalancedStatementMarkerError
+//   - TemplateError
 //   - DuplicateMigrationVersionError
-//   - MissingMigrationVersionError
-func New(db *sql.DB, fs fs.FS) (Migrator, error) {
-	migrations, err := loadMigrations(fs)
+//   - MissingMigrationVersionError, unless WithSparseVersions is used
+//   - InvalidCurrentVersionError
+//   - ChecksumMismatchError, unless WithoutChecksumVerification is used
+func New(db *sql.DB, fs fs.FS, opts ...Option) (Migrator, error) {
+	return NewContext(context.Background(), db, fs, opts...)
+}
+
+// NewContext is like New but uses the given context for database queries.
+func NewContext(ctx context.Context, db *sql.DB, fs fs.FS, opts ...Option) (Migrator, error) {
+	o := newOptions(opts)
+	return newFromSource(ctx, db, o.fsSource(fs), o)
+}
+
+// NewFromSource is like New but loads the migrations from a Source instead of an fs.FS.
+func NewFromSource(db *sql.DB, source Source, opts ...Option) (Migrator, error) {
+	return NewFromSourceContext(context.Background(), db, source, opts...)
+}
+
+// NewFromSourceContext is like NewFromSource but uses the given context to load the migrations
+// and for database queries.
+func NewFromSourceContext(
+	ctx context.Context,
+	db *sql.DB,
+	source Source,
+	opts ...Option,
+) (Migrator, error) {
+	return newFromSource(ctx, db, source, newOptions(opts))
+}
+
+func newFromSource(ctx context.Context, db *sql.DB, source Source, o options) (Migrator, error) {
+	set, err := loadMigrationSet(ctx, source, o)
 	if err != nil {
 		return nil, err
 	}
 
-	lastVersion, err := validateMigrations(migrations)
+	return newMigrator(ctx, db, set, o)
+}
+
+// migrationSet is a validated set of migrations. It is not modified by the migrators using it,
+// so it can be shared between them.
+type migrationSet struct {
+	migrations  []Migration
+	baselines   []Migration
+	lastVersion int64
+}
+
+// loadMigrationSet loads the migrations of every source and the Go migrations, and validates
+// them together.
+func loadMigrationSet(ctx context.Context, source Source, o options) (migrationSet, error) {
+	migrations, err := source.Migrations(ctx)
 	if err != nil {
-		return nil, err
+		return migrationSet{}, err
 	}
 
-	currentVersion, err := getCurrentDBVersion(db)
+	for _, source := range o.sources {
+		sourceMigrations, err := source.source(o).Migrations(ctx)
+		if err != nil {
+			return migrationSet{}, fmt.Errorf("failed to load source %s: %w", source.name, err)
+		}
+
+		for _, migration := range sourceMigrations {
+			migration.source = source.name
+			migrations = append(migrations, migration)
+		}
+	}
+
+	migrations = append(migrations, o.goMigrations...)
+
+	migrations, baselines := partitionBaselines(migrations)
+
+	lastVersion, err := validateMigrations(migrations, o.sparse)
 	if err != nil {
-		return nil, err
+		return migrationSet{}, err
 	}
 
-	if currentVersion > lastVersion {
-		return nil, InvalidCurrentVersionError{Version: currentVersion}
+	err = validateBaselines(baselines, migrations)
+	if err != nil {
+		return migrationSet{}, err
 	}
 
-	return &migrator{
-		db:             db,
-		migrations:     migrations,
-		lastVersion:    lastVersion,
-		currentVersion: currentVersion,
+	return migrationSet{
+		migrations:  migrations,
+		baselines:   baselines,
+		lastVersion: lastVersion,
 	}, nil
 }
+
+// newMigrator returns a migrator applying a set of migrations, and reads its current version.
+func newMigrator(
+	ctx context.Context,
+	db *sql.DB,
+	set migrationSet,
+	o options,
+) (*migrator, error) {
+	m := &migrator{
+		db:          db,
+		logger:      o.logger,
+		dialect:     o.dialect,
+		locker:      withHistoryTable(o.locker, o.dialect, o.schema),
+		dryRun:      o.dryRun,
+		table:       qualifiedTableName(o.dialect, o.schema, o.table),
+		tableName:   o.table,
+		schema:      o.schema,
+		namespace:   o.namespace,
+		sparse:      o.sparse,
+		outOfOrder:  o.outOfOrder,
+		hooks:       o.hooks,
+		migrations:  set.migrations,
+		baselines:   set.baselines,
+		lastVersion: set.lastVersion,
+	}
+
+	err := m.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !o.skipChecksums {
+		err = m.verifyChecksums(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// qualifiedTableName returns the quoted name of a table, qualified by its schema.
+func qualifiedTableName(dialect Dialect, schema string, table string) string {
+	if schema == "" {
+		return dialect.QuoteIdentifier(table)
+	}
+
+	return dialect.QuoteIdentifier(schema) + "." + dialect.QuoteIdentifier(table)
+}
+
+// refresh reads the current version, and the applied versions if they are sparse, from the
+// database.
+func (m *migrator) refresh(ctx context.Context) error {
+	currentVersion, err := m.getCurrentDBVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if currentVersion > m.lastVersion {
+		return InvalidCurrentVersionError{Version: currentVersion}
+	}
+
+	m.currentVersion = currentVersion
+
+	if !m.sparse {
+		return nil
+	}
+
+	history, err := m.getHistory(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.applied = make(map[int64]bool, len(history))
+	for _, row := range history {
+		m.applied[row.version] = true
+	}
+
+	return nil
+}
+
+// isAppliedVersion tells if a version is applied, according to the last refresh.
+func (m *migrator) isAppliedVersion(version int64) bool {
+	if m.sparse {
+		return m.applied[version]
+	}
+
+	return version <= m.currentVersion
+}
+
+// markApplied records that a version was applied.
+func (m *migrator) markApplied(version int64) {
+	if m.sparse {
+		m.applied[version] = true
+	}
+
+	m.currentVersion = max(m.currentVersion, version)
+}
+
+// markRolledBack records that a version was rolled back.
+func (m *migrator) markRolledBack(version int64) {
+	if !m.sparse {
+		m.currentVersion = version - 1
+		return
+	}
+
+	delete(m.applied, version)
+
+	m.currentVersion = 0
+	for v := range m.applied {
+		m.currentVersion = max(m.currentVersion, v)
+	}
+}
+
+// checkPending checks that pending migrations can be applied.
+//
+// It returns an OutOfOrderMigrationError if a migration is older than the latest applied one
+// and it is not allowed, and a SquashedMigrationError if a squash migration must be applied
+// on a database that is not empty.
+func (m *migrator) checkPending(migrations []Migration) error {
+	for _, migration := range migrations {
+		if migration.squash {
+			return SquashedMigrationError{
+				Version:       m.currentVersion,
+				SquashVersion: migration.version,
+			}
+		}
+	}
+
+	if m.outOfOrder {
+		return nil
+	}
+
+	for _, migration := range migrations {
+		if migration.version < m.currentVersion {
+			return OutOfOrderMigrationError{
+				Version:       migration.version,
+				LatestVersion: m.currentVersion,
+			}
+		}
+	}
+
+	return nil
+}
+
+// migration returns the migration with the given version.
+func (m *migrator) migration(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.version == version {
+			return migration, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+// pending returns the migrations not applied yet, up to the given version.
+func (m *migrator) pending(version int64) []Migration {
+	var migrations []Migration
+	for _, migration := range m.migrations {
+		if !m.isAppliedVersion(migration.version) && migration.version <= version {
+			migrations = append(migrations, migration)
+		}
+	}
+
+	return migrations
+}
+
+// appliedAbove returns the applied migrations above the given version, latest first.
+func (m *migrator) appliedAbove(version int64) []Migration {
+	var migrations []Migration
+	for _, migration := range slices.Backward(m.migrations) {
+		if migration.version > version && m.isAppliedVersion(migration.version) {
+			migrations = append(migrations, migration)
+		}
+	}
+
+	return migrations
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"io/fs"
 	"testing"
+	"testing/fstest"
 
 	_ "modernc.org/sqlite"
 
@@ -48,6 +49,10 @@ var invalidMigration1FS = Must(fs.Sub(invalidMigration1RootFS, "test_data/invali
 var invalidMigration2RootFS embed.FS
 var invalidMigration2FS = Must(fs.Sub(invalidMigration2RootFS, "test_data/invalid_migration_2"))
 
+//go:embed test_data/invalid_migration_3/*.sql
+var invalidMigration3RootFS embed.FS
+var invalidMigration3FS = Must(fs.Sub(invalidMigration3RootFS, "test_data/invalid_migration_3"))
+
 //go:embed test_data/migrations_ok/*.sql
 var migrationsOKRootFS embed.FS
 var migrationsOKFS = Must(fs.Sub(migrationsOKRootFS, "test_data/migrations_ok"))
@@ -70,6 +75,16 @@ func getDB(t *testing.T, stmts ...string) *sql.DB {
 	return db
 }
 
+// createTable returns a migration file creating a table, and dropping it when rolled back.
+func createTable(table string) *fstest.MapFile {
+	return &fstest.MapFile{
+		Data: []byte(
+			"-- +migrate Up\nCREATE TABLE " + table + " (id INTEGER PRIMARY KEY);\n" +
+				"-- +migrate Down\nDROP TABLE " + table + ";\n",
+		),
+	}
+}
+
 func getMigrator(t *testing.T, db *sql.DB, migrations fs.FS) migrator.Migrator {
 	t.Helper()
 
@@ -266,6 +281,28 @@ func TestNew_InvalidMigration_2(t *testing.T) {
 	}
 }
 
+func TestNew_InvalidMigration_3(t *testing.T) {
+	// one migration, two down sections
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	_, err := migrator.New(db, invalidMigration3FS)
+	if err == nil {
+		t.Fatalf("expected InvalidMigrationFileError, got no error: %v", err)
+	}
+
+	var invalidErr migrator.InvalidMigrationFileError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected InvalidMigrationFileError, got: %v", err)
+	}
+
+	if invalidErr.Filename != "2_invalid.sql" || invalidErr.Line != 7 {
+		t.Fatalf("expected an error in '2_invalid.sql' at line 7, got: %v", err)
+	}
+}
+
 func TestNew_InvalidCurrentVersion(t *testing.T) {
 	// four migrations, current version is 5
 	t.Parallel()
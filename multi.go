@@ -0,0 +1,182 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Target is a database, or a part of a database, migrated by a MultiRunner.
+type Target struct {
+	// Name identifies the target in the logs and the results, like a tenant name.
+	Name string
+	DB   *sql.DB
+	// Options are added to the options of the MultiRunner for this target, typically
+	// WithNamespace, WithSchema or WithTemplateData.
+	//
+	// With WithTemplateData, the migration files are loaded again for the target and its
+	// templates are rendered with its data, which replaces the data of the MultiRunner. Other
+	// options changing the migrations, like WithFS, WithGoMigration or WithSparseVersions, are
+	// ignored: the migrations are loaded once for all targets.
+	Options []Option
+}
+
+// TargetResult is the result of the migration of a target.
+type TargetResult struct {
+	Name string
+	// Version is the version of the target after the migration, or before it failed.
+	Version int64
+	// Skipped tells if the target was not migrated because another one failed first.
+	Skipped  bool
+	Duration time.Duration
+	Err      error
+}
+
+// MultiRunner applies the same migrations to many targets, like the schemas of a
+// schema-per-tenant database.
+//
+// For PostgreSQL schemas, the statements of the migrations are run in the search_path of the
+// connection. Either open a *sql.DB per tenant with its search_path, or reference the schema
+// in a template and give each target its schema with WithTemplateData in Target.Options.
+type MultiRunner struct {
+	// Parallelism is the maximum number of targets migrated at the same time. Targets are
+	// migrated one by one if it is 0.
+	Parallelism int
+	// ContinueOnError makes the runner migrate every target even if some fail. By default no
+	// target is started after a failure, and the remaining ones are reported as skipped.
+	ContinueOnError bool
+
+	opts   []Option
+	sparse bool
+	set    migrationSet
+	// source returns the source of the migrations, to render them again for a target.
+	source func(o options) Source
+}
+
+// NewMultiRunner loads and validates the migrations, with the same options as New.
+//
+// It returns the same errors as New, but does not connect to any database.
+func NewMultiRunner(fs fs.FS, opts ...Option) (*MultiRunner, error) {
+	return newMultiRunner(func(o options) Source { return o.fsSource(fs) }, opts)
+}
+
+// NewMultiRunnerFromSource is like NewMultiRunner but loads the migrations from a Source.
+//
+// The source is not rendered again for the targets using WithTemplateData, only the files
+// added with WithFS are.
+func NewMultiRunnerFromSource(source Source, opts ...Option) (*MultiRunner, error) {
+	return newMultiRunner(func(options) Source { return source }, opts)
+}
+
+func newMultiRunner(source func(o options) Source, opts []Option) (*MultiRunner, error) {
+	o := newOptions(opts)
+	set, err := loadMigrationSet(context.Background(), source(o), o)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MultiRunner{opts: opts, sparse: o.sparse, set: set, source: source}, nil
+}
+
+// Migrate applies all pending migrations to every target.
+//
+// It returns a result per target, in the same order, and the errors of the failed targets
+// joined. Each of them is a TargetError.
+func (r *MultiRunner) Migrate(targets []Target) ([]TargetResult, error) {
+	return r.MigrateContext(context.Background(), targets)
+}
+
+// MigrateContext is like Migrate but uses the given context.
+func (r *MultiRunner) MigrateContext(
+	ctx context.Context,
+	targets []Target,
+) ([]TargetResult, error) {
+	results := make([]TargetResult, len(targets))
+	semaphore := make(chan struct{}, max(r.Parallelism, 1))
+
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	for i, target := range targets {
+		semaphore <- struct{}{}
+
+		if failed.Load() && !r.ContinueOnError {
+			results[i] = TargetResult{Name: target.Name, Skipped: true}
+			<-semaphore
+			continue
+		}
+
+		wg.Go(func() {
+			defer func() { <-semaphore }()
+
+			results[i] = r.migrate(ctx, target)
+			if results[i].Err != nil {
+				failed.Store(true)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, TargetError{Target: result.Name, Err: result.Err})
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// migrate applies the pending migrations to a target.
+func (r *MultiRunner) migrate(ctx context.Context, target Target) TargetResult {
+	start := time.Now()
+	result := TargetResult{Name: target.Name}
+
+	o := newOptions(append(slices.Clone(r.opts), target.Options...))
+	o.sparse = r.sparse
+	o.logger = o.logger.With("target", target.Name)
+
+	set, err := r.targetSet(ctx, target)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Err = err
+		return result
+	}
+
+	m, err := newMigrator(ctx, target.DB, set, o)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Err = err
+		return result
+	}
+
+	result.Err = m.MigrateContext(ctx)
+	result.Version = m.currentVersion
+	result.Duration = time.Since(start)
+
+	if result.Err != nil {
+		o.logger.Error("failed to migrate target", "error", result.Err)
+	} else {
+		o.logger.Info("target migrated", "version", result.Version)
+	}
+
+	return result
+}
+
+// targetSet returns the migrations of a target. They are loaded again if the target has its
+// own template data.
+func (r *MultiRunner) targetSet(ctx context.Context, target Target) (migrationSet, error) {
+	data := newOptions(target.Options).templateData
+	if data == nil {
+		return r.set, nil
+	}
+
+	o := newOptions(r.opts)
+	o.templateData = data
+	return loadMigrationSet(ctx, r.source(o), o)
+}
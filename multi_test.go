@@ -0,0 +1,190 @@
+package migrator_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+func getTargets(t *testing.T, names ...string) []migrator.Target {
+	t.Helper()
+
+	var targets []migrator.Target
+	for _, name := range names {
+		db := getDB(t)
+		t.Cleanup(func() { _ = db.Close() })
+
+		targets = append(targets, migrator.Target{Name: name, DB: db})
+	}
+
+	return targets
+}
+
+func TestMultiRunner_Migrate(t *testing.T) {
+	t.Parallel()
+
+	runner, err := migrator.NewMultiRunner(migrationsOKFS)
+	if err != nil {
+		t.Fatalf("failed to create runner: %v", err)
+	}
+
+	runner.Parallelism = 2
+	targets := getTargets(t, "acme", "globex", "initech")
+
+	results, err := runner.Migrate(targets)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if len(results) != len(targets) {
+		t.Fatalf("expected %d results, got: %+v", len(targets), results)
+	}
+
+	for i, result := range results {
+		if result.Name != targets[i].Name || result.Version != 4 || result.Skipped {
+			t.Errorf("expected %s to be at version 4, got: %+v", targets[i].Name, result)
+		}
+
+		version, err := getMigrator(t, targets[i].DB, migrationsOKFS).Version()
+		if err != nil || version != 4 {
+			t.Errorf("expected %s database at version 4, got: %d, %v", result.Name, version, err)
+		}
+	}
+}
+
+func TestMultiRunner_TargetOptions(t *testing.T) {
+	t.Parallel()
+
+	runner, err := migrator.NewMultiRunner(migrationsOKFS)
+	if err != nil {
+		t.Fatalf("failed to create runner: %v", err)
+	}
+
+	targets := getTargets(t, "acme")
+	targets[0].Options = []migrator.Option{migrator.WithNamespace("acme")}
+
+	_, err = runner.Migrate(targets)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	m, err := migrator.New(targets[0].DB, migrationsOKFS, migrator.WithNamespace("acme"))
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 4 {
+		t.Fatalf("expected version 4 in namespace acme, got: %d", version)
+	}
+}
+
+func TestMultiRunner_TargetTemplateData(t *testing.T) {
+	t.Parallel()
+
+	runner, err := migrator.NewMultiRunner(
+		templateFS, migrator.WithTemplateData(map[string]any{"tenants": []string{}}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create runner: %v", err)
+	}
+
+	targets := getTargets(t, "acme", "globex")
+	for i := range targets {
+		targets[i].Options = []migrator.Option{
+			migrator.WithTemplateData(map[string]any{"tenants": []string{targets[i].Name}}),
+		}
+	}
+
+	_, err = runner.Migrate(targets)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	for i, target := range targets {
+		other := targets[1-i].Name
+
+		_, err = target.DB.Exec("SELECT id FROM " + target.Name + "_orders")
+		if err != nil {
+			t.Errorf("expected table %s_orders in %s, got: %v", target.Name, target.Name, err)
+		}
+
+		_, err = target.DB.Exec("SELECT id FROM " + other + "_orders")
+		if err == nil {
+			t.Errorf("expected no table %s_orders in %s", other, target.Name)
+		}
+	}
+}
+
+// getFailingTargets returns three targets, the second one failing to migrate.
+func getFailingTargets(t *testing.T) []migrator.Target {
+	t.Helper()
+
+	targets := getTargets(t, "acme", "globex", "initech")
+
+	_, err := targets[1].DB.Exec("CREATE TABLE test_table (id INTEGER)")
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	return targets
+}
+
+func TestMultiRunner_FailFast(t *testing.T) {
+	t.Parallel()
+
+	runner, err := migrator.NewMultiRunner(migrationsOKFS)
+	if err != nil {
+		t.Fatalf("failed to create runner: %v", err)
+	}
+
+	results, err := runner.Migrate(getFailingTargets(t))
+
+	var targetErr migrator.TargetError
+	if !errors.As(err, &targetErr) || targetErr.Target != "globex" {
+		t.Fatalf("expected TargetError for globex, got: %v", err)
+	}
+
+	if results[0].Version != 4 || results[0].Err != nil {
+		t.Errorf("expected acme to be migrated, got: %+v", results[0])
+	}
+
+	if results[1].Version != 0 || results[1].Err == nil {
+		t.Errorf("expected globex to fail, got: %+v", results[1])
+	}
+
+	if !results[2].Skipped || results[2].Version != 0 {
+		t.Errorf("expected initech to be skipped, got: %+v", results[2])
+	}
+}
+
+func TestMultiRunner_ContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	runner, err := migrator.NewMultiRunner(migrationsOKFS)
+	if err != nil {
+		t.Fatalf("failed to create runner: %v", err)
+	}
+
+	runner.ContinueOnError = true
+	targets := getFailingTargets(t)
+
+	results, err := runner.Migrate(targets)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if results[1].Err == nil || results[2].Skipped || results[2].Version != 4 {
+		t.Fatalf("expected only globex to fail, got: %+v", results)
+	}
+
+	_, err = targets[2].DB.Exec("SELECT * FROM test_table")
+	if err != nil {
+		t.Fatalf("expected test_table to exist in initech, got: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package migrator_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+var libraryFS = fstest.MapFS{
+	"1_library_table.sql": &fstest.MapFile{
+		Data: []byte("-- +migrate Up\nCREATE TABLE library_table (id INTEGER PRIMARY KEY);\n"),
+	},
+}
+
+func TestNamespace(t *testing.T) {
+	t.Parallel()
+
+	db, app := getDBAndMigrator(t, migrationsOKFS)
+	defer db.Close()
+
+	err := app.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	library, err := migrator.New(db, libraryFS, migrator.WithNamespace("library"))
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	version, err := library.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 0 {
+		t.Fatalf("expected version 0, got: %d", version)
+	}
+
+	err = library.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	_, err = db.Exec(`SELECT id FROM library_table`)
+	if err != nil {
+		t.Fatalf("expected library_table to exist, got error: %v", err)
+	}
+
+	version, err = app.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 4 {
+		t.Fatalf("expected version 4, got: %d", version)
+	}
+
+	err = library.Rollback(0)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = 1`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected version 1 to be recorded in both namespaces, got %d rows", count)
+	}
+}
+
+func TestNamespace_LegacyTable(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(
+		t,
+		`CREATE TABLE schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO schema_migrations (version, applied_at) VALUES (1, '2020-01-02 03:04:05')`,
+		`INSERT INTO schema_migrations (version, applied_at) VALUES (2, '2020-01-02 03:04:05')`,
+	)
+	defer db.Close()
+
+	m := getMigrator(t, db, migrationsOKFS)
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	appliedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !statuses[1].Applied || !statuses[1].AppliedAt.Equal(appliedAt) || statuses[2].Applied {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE namespace = ''`).
+		Scan(&count)
+	if err != nil {
+		t.Fatalf("expected a namespace column, got error: %v", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected 2 rows in the default namespace, got: %d", count)
+	}
+
+	library, err := migrator.New(db, libraryFS, migrator.WithNamespace("library"))
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = library.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+}
+
+func TestNamespace_InterruptedUpgrade(t *testing.T) {
+	t.Parallel()
+
+	newTable := func(table string) string {
+		return migrator.SQLite.CreateHistoryTableSQL(table)
+	}
+
+	tests := []struct {
+		name  string
+		stmts []string
+	}{
+		{
+			name: "before dropping the table",
+			stmts: []string{
+				"CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY)",
+				"INSERT INTO schema_migrations (version) VALUES (1), (2)",
+				newTable("schema_migrations_upgrade"),
+				"INSERT INTO schema_migrations_upgrade (version) VALUES (1)",
+			},
+		},
+		{
+			name: "after dropping the table",
+			stmts: []string{
+				newTable("schema_migrations_upgrade"),
+				"INSERT INTO schema_migrations_upgrade (version) VALUES (1), (2)",
+			},
+		},
+		{
+			name: "while copying back the rows",
+			stmts: []string{
+				newTable("schema_migrations"),
+				"INSERT INTO schema_migrations (version) VALUES (1)",
+				newTable("schema_migrations_upgrade"),
+				"INSERT INTO schema_migrations_upgrade (version) VALUES (1), (2)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db := getDB(t, tt.stmts...)
+			defer db.Close()
+
+			m := getMigrator(t, db, migrationsOKFS)
+
+			version, err := m.Version()
+			if err != nil {
+				t.Fatalf("failed to get current version: %v", err)
+			}
+
+			if version != 2 {
+				t.Fatalf("expected version 2, got: %d", version)
+			}
+
+			var count int
+			err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE namespace = ''`).
+				Scan(&count)
+			if err != nil || count != 2 {
+				t.Fatalf("expected 2 rows in the default namespace, got: %d, %v", count, err)
+			}
+
+			_, err = db.Exec("SELECT 1 FROM schema_migrations_upgrade")
+			if err == nil {
+				t.Fatal("expected the upgrade table to be dropped")
+			}
+		})
+	}
+}
+
+// countingLocker counts how many times the lock is acquired.
+type countingLocker struct {
+	count *int
+}
+
+func (l countingLocker) Lock(context.Context, *sql.DB) (func(context.Context) error, error) {
+	*l.count++
+	return func(context.Context) error { return nil }, nil
+}
+
+func TestNamespace_UpgradeLocked(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(
+		t,
+		"CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY)",
+	)
+	defer db.Close()
+
+	var count int
+	m, err := migrator.New(db, migrationsOKFS, migrator.WithLocker(countingLocker{&count}))
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected the upgrade to acquire the lock once, got: %d", count)
+	}
+
+	_, err = m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected the upgraded table not to be locked again, got: %d", count)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected migrating to acquire the lock once, got: %d", count)
+	}
+}
@@ -0,0 +1,99 @@
+package migrator_test
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+//go:embed test_data/no_transaction/*.sql
+var noTransactionRootFS embed.FS
+var noTransactionFS = Must(fs.Sub(noTransactionRootFS, "test_data/no_transaction"))
+
+//go:embed test_data/no_transaction_failure/*.sql
+var noTransactionFailureRootFS embed.FS
+var noTransactionFailureFS = Must(
+	fs.Sub(noTransactionFailureRootFS, "test_data/no_transaction_failure"),
+)
+
+func TestMigrate_NoTransaction(t *testing.T) {
+	// VACUUM cannot run in a transaction
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, noTransactionFS)
+	defer db.Close()
+
+	err := m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 2 {
+		t.Fatalf("expected version 2, got: %d", version)
+	}
+
+	err = m.Rollback(1)
+	if err != nil {
+		t.Fatalf("failed to roll back migration: %v", err)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM test_table`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows in test_table: %v", err)
+	}
+
+	if count != 0 {
+		t.Fatalf("expected test_table to be empty, got %d rows", count)
+	}
+}
+
+func TestMigrate_NoTransactionFailure(t *testing.T) {
+	// the second statement fails, the first one is not rolled back
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, noTransactionFailureFS)
+	defer db.Close()
+
+	err := m.Migrate()
+
+	var noTxErr migrator.NonTransactionalMigrationError
+	if !errors.As(err, &noTxErr) {
+		t.Fatalf("expected NonTransactionalMigrationError, got: %v", err)
+	}
+
+	if noTxErr.Version != 2 || noTxErr.Statement != 2 {
+		t.Fatalf(
+			"expected statement 2 of migration 2 to fail, got statement %d of migration %d",
+			noTxErr.Statement,
+			noTxErr.Version,
+		)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 1 {
+		t.Fatalf("expected version 1, got: %d", version)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM test_table`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows in test_table: %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected one row in test_table, got: %d", count)
+	}
+}
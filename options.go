@@ -0,0 +1,260 @@
+package migrator
+
+import (
+	"io/fs"
+	"log/slog"
+)
+
+// Option configures a Migrator created by New.
+type Option func(*options)
+
+type options struct {
+	logger  *slog.Logger
+	dialect Dialect
+	locker  Locker
+	dryRun  bool
+	table   string
+	schema  string
+	// namespace separates the migrations of several migrators in the same history table.
+	namespace  string
+	sparse     bool
+	outOfOrder bool
+	hooks      hooks
+	// skipChecksums disables the verification of the checksums by New.
+	skipChecksums bool
+
+	// sources are loaded in addition to the fs.FS or the Source given to New.
+	sources      []namedSource
+	recursive    bool
+	templateData map[string]any
+
+	goMigrations []Migration
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		logger:  slog.New(slog.DiscardHandler),
+		dialect: SQLite,
+		locker:  noopLocker{},
+		table:   defaultHistoryTable,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithLogger sets the logger used to report migration events.
+//
+// By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithDialect sets the dialect used for the queries on the history table.
+//
+// The default dialect is SQLite.
+func WithDialect(dialect Dialect) Option {
+	return func(o *options) {
+		o.dialect = dialect
+	}
+}
+
+// WithTableName sets the name of the history table recording the applied migrations.
+//
+// The name is quoted, so it is case sensitive and can contain any character.
+// The default name is "schema_migrations".
+func WithTableName(name string) Option {
+	return func(o *options) {
+		o.table = name
+	}
+}
+
+// WithSchema sets the schema of the history table, for databases supporting them like
+// PostgreSQL or SQL Server. With MySQL it is the database of the table.
+//
+// By default the table is not qualified, and the database picks the schema.
+func WithSchema(schema string) Option {
+	return func(o *options) {
+		o.schema = schema
+	}
+}
+
+// WithNamespace sets the namespace of the migrations, so that several independent sets of
+// migrations can share the same history table without their versions colliding.
+//
+// Only the rows of the history table in the namespace are considered. The default namespace
+// is the empty string.
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithSparseVersions allows gaps between migration versions, for example to use timestamps
+// like 20261017120000_add_users.sql as versions.
+//
+// A migration is then applied if its version is recorded in the history table, instead of if
+// it is lower than the current version. Migrations older than the latest applied one are
+// rejected with an OutOfOrderMigrationError, unless WithOutOfOrder is used.
+func WithSparseVersions() Option {
+	return func(o *options) {
+		o.sparse = true
+	}
+}
+
+// WithOutOfOrder allows applying migrations older than the latest applied one, when using
+// WithSparseVersions. This happens when migrations are created in parallel branches.
+func WithOutOfOrder() Option {
+	return func(o *options) {
+		o.outOfOrder = true
+	}
+}
+
+// namedSource is a source of migrations added with WithFS or WithSource.
+type namedSource struct {
+	name string
+	// source returns the source, once all options are known.
+	source func(o options) Source
+}
+
+// fsSource returns the source of the migration files of an fs.FS, using the options for
+// these files.
+func (o options) fsSource(directory fs.FS) Source {
+	return FSSource{FS: directory, Recursive: o.recursive, TemplateData: o.templateData}
+}
+
+// WithFS adds migration files from another fs.FS, like the migrations of a plugin shipped in a
+// separate package. It can be used several times.
+//
+// The migrations of every source are merged in a single ordered set, so their versions must be
+// unique across all of them. The name identifies the source in errors and in Status.
+func WithFS(name string, directory fs.FS) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, namedSource{
+			name:   name,
+			source: func(o options) Source { return o.fsSource(directory) },
+		})
+	}
+}
+
+// WithSource adds migrations from another Source, like WithFS.
+func WithSource(name string, source Source) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, namedSource{
+			name:   name,
+			source: func(options) Source { return source },
+		})
+	}
+}
+
+// WithRecursive makes migration files be searched in the subdirectories too, so they can be
+// organized like "2026/10/20261017120000_add_users.sql" or per module.
+//
+// Only the base name of the files must match FilenameRgx, and versions are unique across the
+// whole tree. By default only the root directory is read.
+func WithRecursive() Option {
+	return func(o *options) {
+		o.recursive = true
+	}
+}
+
+// WithTemplateData sets the data used to render the migration files ending with ".sql.tmpl",
+// like schema names or table prefixes.
+//
+// Templates use the text/template syntax, for example {{.schema}}. Using a key missing from
+// the data is an error.
+func WithTemplateData(data map[string]any) Option {
+	return func(o *options) {
+		o.templateData = data
+	}
+}
+
+// WithoutChecksumVerification makes New accept applied migrations whose file was modified,
+// instead of returning a ChecksumMismatchError, so that Repair can be called to accept the
+// changes. Migrate, MigrateTo, Rollback and Plan still verify the checksums.
+func WithoutChecksumVerification() Option {
+	return func(o *options) {
+		o.skipChecksums = true
+	}
+}
+
+// WithLocker sets the lock acquired while migrating, to prevent several processes from
+// migrating the same database at the same time.
+//
+// By default no lock is used.
+func WithLocker(locker Locker) Option {
+	return func(o *options) {
+		o.locker = locker
+	}
+}
+
+// WithGoMigration adds a migration written in Go.
+//
+// Go migrations are merged with the migration files, and their versions must follow the same
+// rules. The down function can be nil if the migration cannot be rolled back.
+func WithGoMigration(version int64, name string, up, down GoMigrationFunc) Option {
+	return func(o *options) {
+		o.goMigrations = append(o.goMigrations, NewGoMigration(version, name, up, down))
+	}
+}
+
+// WithDryRun makes Migrate, MigrateTo and Rollback log the migrations and their statements
+// instead of executing them, and Repair log the checksums it would update. Nothing is recorded
+// in the history table.
+//
+// The history table is not created nor upgraded either, a missing table is handled as an empty
+// one, and the lock set with WithLocker is not acquired. If the table was created by an older
+// version and must be upgraded, New returns a HistoryTableUpgradeError.
+//
+// The logger must be set with WithLogger to see them, see also Migrator.Plan.
+func WithDryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// WithBeforeMigration adds a hook called before each migration is applied or rolled back, in
+// its transaction. If the hook returns an error, the migration is rolled back.
+func WithBeforeMigration(hook MigrationHook) Option {
+	return func(o *options) {
+		o.hooks.beforeMigration = append(o.hooks.beforeMigration, hook)
+	}
+}
+
+// WithAfterMigration adds a hook called after each migration is applied or rolled back, in its
+// transaction before it is committed. If the hook returns an error, the migration is rolled
+// back, except if it is run without transaction, see MigrationHook.
+func WithAfterMigration(hook MigrationHook) Option {
+	return func(o *options) {
+		o.hooks.afterMigration = append(o.hooks.afterMigration, hook)
+	}
+}
+
+// WithOnError adds a hook called when a migration fails to be applied or rolled back, after
+// its transaction is rolled back.
+func WithOnError(hook ErrorHook) Option {
+	return func(o *options) {
+		o.hooks.onError = append(o.hooks.onError, hook)
+	}
+}
+
+// WithBeforeRun adds a hook called at the start of each call to Migrate, MigrateTo and
+// Rollback, after the lock is acquired. If the hook returns an error, no migration is run.
+func WithBeforeRun(hook RunHook) Option {
+	return func(o *options) {
+		o.hooks.beforeRun = append(o.hooks.beforeRun, hook)
+	}
+}
+
+// WithAfterRun adds a hook called at the end of each successful call to Migrate, MigrateTo and
+// Rollback, before the lock is released.
+func WithAfterRun(hook RunHook) Option {
+	return func(o *options) {
+		o.hooks.afterRun = append(o.hooks.afterRun, hook)
+	}
+}
@@ -0,0 +1,72 @@
+package migrator
+
+import "context"
+
+// PlannedMigration is a migration that Migrate would apply.
+type PlannedMigration struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+	// Statements are the SQL statements of the migration. It is empty for Go migrations.
+	Statements []string `json:"statements"`
+	// Go tells if the migration is a Go migration.
+	Go bool `json:"go"`
+	// Transactional tells if the migration is applied in a transaction.
+	Transactional bool `json:"transactional"`
+	// Baseline tells if the migration is a baseline, applied instead of all the migrations up
+	// to its version.
+	Baseline bool `json:"baseline,omitempty"`
+}
+
+func (m *migrator) Plan() ([]PlannedMigration, error) {
+	return m.PlanContext(context.Background())
+}
+
+func (m *migrator) PlanContext(ctx context.Context) ([]PlannedMigration, error) {
+	err := m.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.verifyChecksums(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := m.pending(m.lastVersion)
+	if baseline, ok := m.baselineFor(m.lastVersion); ok {
+		migrations = append([]Migration{baseline}, above(migrations, baseline.version)...)
+	}
+
+	err = m.checkPending(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []PlannedMigration
+	for _, migration := range migrations {
+		plan = append(plan, PlannedMigration{
+			Version:       migration.version,
+			Name:          migration.name,
+			Statements:    migration.upSQL,
+			Go:            migration.upFunc != nil,
+			Transactional: !migration.noTransaction,
+			Baseline:      migration.baseline || migration.squash,
+		})
+	}
+
+	return plan, nil
+}
+
+// logDryRun logs a migration that would be applied or rolled back, with its statements.
+func (m *migrator) logDryRun(msg string, migration Migration, statements []string) {
+	m.logger.Info(
+		msg,
+		"version", migration.version,
+		"name", migration.name,
+		"transactional", !migration.noTransaction,
+	)
+
+	for _, statement := range statements {
+		m.logger.Info("dry run statement", "version", migration.version, "statement", statement)
+	}
+}
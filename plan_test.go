@@ -0,0 +1,232 @@
+package migrator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsOKFS)
+	defer db.Close()
+
+	err := m.MigrateTo(2)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	plan, err := m.Plan()
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+
+	if len(plan) != 2 {
+		t.Fatalf("expected two planned migrations, got: %d", len(plan))
+	}
+
+	planned := plan[1]
+	expected := []string{
+		"INSERT INTO test_table (id, name) VALUES (1, 'Test Name 1');",
+		"INSERT INTO test_table (id, name) VALUES (2, 'Test Name 2');",
+	}
+	if planned.Version != 4 ||
+		planned.Name != "two_queries" ||
+		!planned.Transactional ||
+		!slices.Equal(planned.Statements, expected) {
+		t.Fatalf("unexpected planned migration: %+v", planned)
+	}
+}
+
+func TestPlan_NoTransaction(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, noTransactionFS)
+	defer db.Close()
+
+	plan, err := m.Plan()
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+
+	if len(plan) != 2 || !plan[0].Transactional || plan[1].Transactional {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestMigrate_DryRun(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	m, err := migrator.New(
+		db, migrationsOKFS, migrator.WithDryRun(), migrator.WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 0 {
+		t.Fatalf("expected version 0, got: %d", version)
+	}
+
+	_, err = db.Exec(`SELECT id FROM test_table`)
+	if err == nil {
+		t.Fatalf("expected test_table to not exist, but it does")
+	}
+
+	_, err = db.Exec(`SELECT 1 FROM schema_migrations`)
+	if err == nil {
+		t.Fatalf("expected the history table to not be created in dry run mode")
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 4 || statuses[0].Applied {
+		t.Fatalf("expected 4 pending migrations, got: %+v", statuses)
+	}
+
+	var statements []string
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record map[string]any
+		err = decoder.Decode(&record)
+		if err != nil {
+			t.Fatalf("failed to decode log record: %v", err)
+		}
+
+		if record["msg"] == "dry run statement" {
+			statements = append(statements, record["statement"].(string))
+		}
+	}
+
+	if len(statements) != 5 || !strings.HasPrefix(statements[0], "CREATE TABLE test_table") {
+		t.Fatalf("unexpected logged statements: %q", statements)
+	}
+}
+
+func TestMigrate_DryRunLock(t *testing.T) {
+	// the lock table is not created in dry run mode
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(
+		db,
+		migrationsOKFS,
+		migrator.WithDryRun(),
+		migrator.WithLocker(migrator.TableLocker{}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	_, err = db.Exec(`SELECT 1 FROM schema_migrations_lock`)
+	if err == nil {
+		t.Fatalf("expected the lock table to not be created in dry run mode")
+	}
+}
+
+func TestRepair_DryRun(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsOKFS)
+	defer db.Close()
+
+	err := m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	_, err = db.Exec("UPDATE schema_migrations SET checksum = 'x'")
+	if err != nil {
+		t.Fatalf("failed to change checksums: %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	m, err = migrator.New(
+		db,
+		migrationsOKFS,
+		migrator.WithDryRun(),
+		migrator.WithLogger(logger),
+		migrator.WithoutChecksumVerification(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Repair()
+	if err != nil {
+		t.Fatalf("failed to repair: %v", err)
+	}
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE checksum = 'x'").
+		Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+
+	if count != 4 {
+		t.Fatalf("expected the checksums to not be updated in dry run mode, got %d", count)
+	}
+
+	if !strings.Contains(buf.String(), "dry run: would update migration checksum") {
+		t.Fatalf("expected the checksum updates to be logged, got: %s", buf.String())
+	}
+}
+
+func TestNew_DryRunLegacyTable(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(
+		t,
+		"CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY)",
+		"INSERT INTO schema_migrations (version) VALUES (1)",
+	)
+	defer db.Close()
+
+	_, err := migrator.New(db, migrationsOKFS, migrator.WithDryRun())
+
+	var upgradeErr migrator.HistoryTableUpgradeError
+	if !errors.As(err, &upgradeErr) {
+		t.Fatalf("expected HistoryTableUpgradeError, got: %v", err)
+	}
+
+	_, err = db.Exec("SELECT checksum FROM schema_migrations")
+	if err == nil {
+		t.Fatal("expected the history table to not be upgraded in dry run mode")
+	}
+}
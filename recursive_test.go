@@ -0,0 +1,124 @@
+package migrator_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+var recursiveFS = fstest.MapFS{
+	"1_users.sql":                 createTable("users"),
+	"billing/2_invoices.sql":      createTable("invoices"),
+	"billing/legacy/3_taxes.sql":  createTable("taxes"),
+	"billing/README.md":           &fstest.MapFile{Data: []byte("# Billing\n")},
+	"shipping/4_parcels.sql.tmpl": createTable("{{.prefix}}parcels"),
+}
+
+func TestNew_NotRecursive(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m := getMigrator(t, db, recursiveFS)
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 1 || statuses[0].Name != "users" {
+		t.Fatalf("expected only the migration of the root directory, got: %+v", statuses)
+	}
+}
+
+func TestMigrate_Recursive(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(
+		db,
+		recursiveFS,
+		migrator.WithRecursive(),
+		migrator.WithTemplateData(map[string]any{"prefix": "eu_"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	for _, table := range []string{"users", "invoices", "taxes", "eu_parcels"} {
+		_, err = db.Exec("SELECT id FROM " + table)
+		if err != nil {
+			t.Errorf("expected table %s to exist, got: %v", table, err)
+		}
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 4 || statuses[2].Name != "taxes" {
+		t.Fatalf("expected the migration names without directory, got: %+v", statuses)
+	}
+}
+
+func TestNew_RecursiveDuplicatedVersion(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"1_users.sql":            createTable("users"),
+		"billing/2_invoices.sql": createTable("invoices"),
+		"shipping/2_parcels.sql": createTable("parcels"),
+	}
+
+	err := migrator.Validate(fsys, migrator.WithRecursive())
+
+	var dupErr migrator.DuplicateMigrationVersionError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected DuplicateMigrationVersionError, got: %v", err)
+	}
+
+	expected := [2]string{"billing/2_invoices.sql", "shipping/2_parcels.sql"}
+	if dupErr.Version != 2 || dupErr.Filenames != expected {
+		t.Fatalf("expected version 2 in %v, got: %v", expected, err)
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     []migrator.Option
+		expected int64
+	}{
+		{name: "root directory", expected: 1},
+		{name: "recursive", opts: []migrator.Option{migrator.WithRecursive()}, expected: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// the template is not rendered, so it does not need its data
+			version, err := migrator.LatestVersion(recursiveFS, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to get latest version: %v", err)
+			}
+
+			if version != tt.expected {
+				t.Fatalf("expected version %d, got: %d", tt.expected, version)
+			}
+		})
+	}
+}
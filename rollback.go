@@ -0,0 +1,159 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (m *migrator) Rollback(steps int) error {
+	return m.RollbackContext(context.Background(), steps)
+}
+
+func (m *migrator) RollbackContext(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func() error {
+		applied := m.appliedAbove(0)
+		if steps < 0 || steps > len(applied) {
+			return InvalidRollbackStepsError{Steps: steps, Applied: len(applied)}
+		}
+
+		// roll back the migrations above the first one to keep
+		var version int64
+		if steps < len(applied) {
+			version = applied[steps].version
+		}
+
+		return m.run(ctx, func() error {
+			return m.migrateDown(ctx, version)
+		})
+	})
+}
+
+func (m *migrator) migrateDown(ctx context.Context, version int64) error {
+	migrations := m.appliedAbove(version)
+
+	// check every migration can be rolled back before touching the database
+	for _, migration := range migrations {
+		if !migration.hasDown || migration.squash {
+			return MissingDownMigrationError{Version: migration.version}
+		}
+	}
+
+	for _, migration := range migrations {
+		if m.dryRun {
+			m.logDryRun("dry run: would roll back migration", migration, migration.downSQL)
+			continue
+		}
+
+		start := time.Now()
+
+		err := m.rollbackMigration(ctx, migration)
+		if err != nil {
+			m.logger.Error(
+				"failed to roll back migration",
+				"version", migration.version,
+				"name", migration.name,
+				"duration", time.Since(start),
+				"error", err,
+			)
+
+			m.callErrorHooks(ctx, migration.info(true), err)
+			return wrapInterrupted(ctx, migration.version, err)
+		}
+
+		m.markRolledBack(migration.version)
+
+		m.logger.Info(
+			"migration rolled back",
+			"version", migration.version,
+			"name", migration.name,
+			"duration", time.Since(start),
+		)
+	}
+
+	return nil
+}
+
+func (m *migrator) rollbackMigration(ctx context.Context, migration Migration) error {
+	m.logger.Info("rolling back migration", "version", migration.version, "name", migration.name)
+
+	if migration.noTransaction {
+		return m.rollbackWithoutTransaction(ctx, migration)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to begin transaction for migration %d: %w", migration.version, err,
+		)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	err = m.beforeMigration(ctx, tx, migration.info(true))
+	if err != nil {
+		return err
+	}
+
+	for _, sql := range migration.downSQL {
+		_, err = tx.ExecContext(ctx, sql)
+		if err != nil {
+			return fmt.Errorf("failed to roll back migration %d: %w", migration.version, err)
+		}
+	}
+
+	if migration.downFunc != nil {
+		err = migration.downFunc(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to roll back migration %d: %w", migration.version, err)
+		}
+	}
+
+	err = m.unrecordMigration(ctx, tx, migration.version)
+	if err != nil {
+		return err
+	}
+
+	err = m.afterMigration(ctx, tx, migration.info(true))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit rollback of migration %d: %w", migration.version, err)
+	}
+
+	return nil
+}
+
+// rollbackWithoutTransaction rolls back a migration outside of any transaction, on a single
+// connection.
+func (m *migrator) rollbackWithoutTransaction(ctx context.Context, migration Migration) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to get a connection for migration %d: %w", migration.version, err,
+		)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	err = m.beforeMigration(ctx, nil, migration.info(true))
+	if err != nil {
+		return err
+	}
+
+	err = execWithoutTransaction(ctx, conn, migration.version, migration.downSQL)
+	if err != nil {
+		return err
+	}
+
+	// the migration stays recorded if the after hook fails, like if a statement failed
+	err = m.afterMigration(ctx, nil, migration.info(true))
+	if err != nil {
+		return err
+	}
+
+	return m.unrecordMigration(ctx, conn, migration.version)
+}
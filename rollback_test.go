@@ -0,0 +1,162 @@
+package migrator_test
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+//go:embed test_data/migrations_down/*.sql
+var migrationsDownRootFS embed.FS
+var migrationsDownFS = Must(fs.Sub(migrationsDownRootFS, "test_data/migrations_down"))
+
+func TestRollback_OK(t *testing.T) {
+	// apply all migrations, then roll back the last two
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsDownFS)
+	defer db.Close()
+
+	err := m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	err = m.Rollback(2)
+	if err != nil {
+		t.Fatalf("failed to roll back migrations: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 1 {
+		t.Fatalf("expected version 1, got: %d", version)
+	}
+
+	// check that test_table exists without the description column
+	_, err = db.Exec(`SELECT id, name FROM test_table`)
+	if err != nil {
+		t.Fatalf("expected test_table to exist, got error: %v", err)
+	}
+
+	_, err = db.Exec(`SELECT description FROM test_table`)
+	if err == nil {
+		t.Fatalf("expected description column to not exist, but it does")
+	}
+
+	// check that another_test_table does not exist
+	_, err = db.Exec(`SELECT id, name FROM another_test_table`)
+	if err == nil {
+		t.Fatalf("expected another_test_table to not exist, but it does")
+	}
+}
+
+func TestRollback_MissingDown(t *testing.T) {
+	// migrations without down section cannot be rolled back
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsOKFS)
+	defer db.Close()
+
+	err := m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	err = m.Rollback(1)
+
+	var missingErr migrator.MissingDownMigrationError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("expected MissingDownMigrationError, got: %v", err)
+	}
+
+	if missingErr.Version != 4 {
+		t.Fatalf("expected missing down migration '4', got: %d", missingErr.Version)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 4 {
+		t.Fatalf("expected version 4, got: %d", version)
+	}
+}
+
+func TestRollback_TooManySteps(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsDownFS)
+	defer db.Close()
+
+	err := m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	err = m.Rollback(4)
+
+	var invalidErr migrator.InvalidRollbackStepsError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected InvalidRollbackStepsError, got: %v", err)
+	}
+
+	if invalidErr.Steps != 4 || invalidErr.Applied != 3 {
+		t.Fatalf("expected 4 steps for 3 applied migrations, got: %+v", invalidErr)
+	}
+}
+
+func TestMigrateTo(t *testing.T) {
+	// migrate up to version 2, then up to 3, then down to 0
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsDownFS)
+	defer db.Close()
+
+	for _, target := range []int64{2, 3, 0} {
+		err := m.MigrateTo(target)
+		if err != nil {
+			t.Fatalf("failed to migrate to version %d: %v", target, err)
+		}
+
+		version, err := m.Version()
+		if err != nil {
+			t.Fatalf("failed to get current version: %v", err)
+		}
+
+		if version != target {
+			t.Fatalf("expected version %d, got: %d", target, version)
+		}
+	}
+
+	// check that test_table does not exist anymore
+	_, err := db.Exec(`SELECT id, name FROM test_table`)
+	if err == nil {
+		t.Fatalf("expected test_table to not exist, but it does")
+	}
+}
+
+func TestMigrateTo_InvalidVersion(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsDownFS)
+	defer db.Close()
+
+	err := m.MigrateTo(4)
+
+	var invalidErr migrator.InvalidTargetVersionError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected InvalidTargetVersionError, got: %v", err)
+	}
+
+	if invalidErr.Version != 4 {
+		t.Fatalf("expected invalid target version '4', got: %d", invalidErr.Version)
+	}
+}
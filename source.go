@@ -0,0 +1,53 @@
+package migrator
+
+import (
+	"context"
+	"io/fs"
+	"slices"
+)
+
+// Source provides the migrations to a Migrator, see NewFromSource.
+//
+// Migrations can come from an fs.FS with FSSource, from a slice with SliceSource, or from
+// anything else, like a table of pending scripts, using ParseMigration or NewGoMigration.
+type Source interface {
+	// Migrations returns the parsed migrations, in any order. They are validated by
+	// NewFromSource.
+	Migrations(ctx context.Context) ([]Migration, error)
+}
+
+// FSSource loads the migration files of an fs.FS. It is the source used by New.
+type FSSource struct {
+	FS fs.FS
+	// Recursive makes migration files be searched in the subdirectories too, see WithRecursive.
+	Recursive bool
+	// TemplateData is used to render the ".sql.tmpl" files, see WithTemplateData.
+	TemplateData map[string]any
+}
+
+// Migrations implements Source.
+func (s FSSource) Migrations(ctx context.Context) ([]Migration, error) {
+	return loadMigrations(s.FS, s.Recursive, s.TemplateData)
+}
+
+// SliceSource returns a fixed list of migrations, for example in tests.
+type SliceSource []Migration
+
+// Migrations implements Source.
+func (s SliceSource) Migrations(ctx context.Context) ([]Migration, error) {
+	// the migrations are sorted when validated
+	return slices.Clone(s), nil
+}
+
+// NewGoMigration returns a migration written in Go, like the ones added with WithGoMigration.
+//
+// The down function can be nil if the migration cannot be rolled back.
+func NewGoMigration(version int64, name string, up, down GoMigrationFunc) Migration {
+	return Migration{
+		version:  version,
+		name:     name,
+		upFunc:   up,
+		downFunc: down,
+		hasDown:  down != nil,
+	}
+}
@@ -0,0 +1,153 @@
+package migrator_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+func parseMigration(t *testing.T, filename string, content string) migrator.Migration {
+	t.Helper()
+
+	migration, err := migrator.ParseMigration(filename, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to parse migration %s: %v", filename, err)
+	}
+
+	return migration
+}
+
+func TestNewFromSource_SliceSource(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	source := migrator.SliceSource{
+		migrator.NewGoMigration(
+			2,
+			"insert_user",
+			func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "INSERT INTO users (id) VALUES (1)")
+				return err
+			},
+			nil,
+		),
+		parseMigration(
+			t,
+			"1_users.sql",
+			"-- +migrate Up\nCREATE TABLE users (id INTEGER PRIMARY KEY);\n"+
+				"-- +migrate Down\nDROP TABLE users;\n",
+		),
+	}
+
+	m, err := migrator.NewFromSource(db, source)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 user, got: %d, %v", count, err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 2 || statuses[0].Name != "users" || statuses[1].Name != "insert_user" {
+		t.Fatalf("expected the migrations users and insert_user, got: %+v", statuses)
+	}
+}
+
+func TestNewFromSource_FSSource(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	source := migrator.FSSource{
+		FS:           fstest.MapFS{"sub/1_users.sql.tmpl": createTable("{{.prefix}}users")},
+		Recursive:    true,
+		TemplateData: map[string]any{"prefix": "app_"},
+	}
+
+	m, err := migrator.NewFromSource(
+		db,
+		source,
+		migrator.WithSource("plugins", migrator.SliceSource{
+			parseMigration(t, "2_plugins.sql", "-- +migrate Up\nCREATE TABLE plugins (id INTEGER);\n"),
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	for _, table := range []string{"app_users", "plugins"} {
+		_, err = db.Exec("SELECT id FROM " + table)
+		if err != nil {
+			t.Errorf("expected table %s to exist, got: %v", table, err)
+		}
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 2 || statuses[1].Source != "plugins" {
+		t.Fatalf("expected migration 2 to come from plugins, got: %+v", statuses)
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) Migrations(context.Context) ([]migrator.Migration, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestNewFromSource_Errors(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	_, err := migrator.NewFromSource(db, failingSource{})
+	if err == nil || err.Error() != "connection refused" {
+		t.Fatalf("expected the error of the source, got: %v", err)
+	}
+
+	_, err = migrator.ParseMigration("users.sql", strings.NewReader("-- +migrate Up\n"))
+
+	var invalidErr migrator.InvalidMigrationFilenameError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected InvalidMigrationFilenameError, got: %v", err)
+	}
+
+	_, err = migrator.NewFromSource(db, migrator.SliceSource{
+		parseMigration(t, "1_a.sql", "-- +migrate Up\nCREATE TABLE a (id INTEGER);\n"),
+		parseMigration(t, "1_b.sql", "-- +migrate Up\nCREATE TABLE b (id INTEGER);\n"),
+	})
+
+	var dupErr migrator.DuplicateMigrationVersionError
+	if !errors.As(err, &dupErr) || dupErr.Filenames != [2]string{"1_a.sql", "1_b.sql"} {
+		t.Fatalf("expected DuplicateMigrationVersionError for 1_a.sql and 1_b.sql, got: %v", err)
+	}
+}
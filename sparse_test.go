@@ -0,0 +1,169 @@
+package migrator_test
+
+import (
+	"errors"
+	"maps"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+var sparseFS = fstest.MapFS{
+	"20261001000000_a.sql": createTable("a"),
+	"20261003000000_c.sql": createTable("c"),
+}
+
+// sparseLateFS contains a migration created in a parallel branch and merged after
+// 20261003000000_c.sql was applied.
+var sparseLateFS = func() fstest.MapFS {
+	fsys := maps.Clone(sparseFS)
+	fsys["20261002000000_b.sql"] = createTable("b")
+	return fsys
+}()
+
+func TestNew_SparseVersionsRequireOption(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	_, err := migrator.New(db, sparseFS)
+
+	var missingErr migrator.MissingMigrationVersionError
+	if !errors.As(err, &missingErr) || missingErr.Version != 1 {
+		t.Fatalf("expected MissingMigrationVersionError for version 1, got: %v", err)
+	}
+
+	err = migrator.Validate(sparseFS, migrator.WithSparseVersions())
+	if err != nil {
+		t.Fatalf("expected sparse migrations to be valid, got: %v", err)
+	}
+}
+
+func TestMigrate_SparseVersions(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(db, sparseFS, migrator.WithSparseVersions())
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 20261003000000 {
+		t.Fatalf("expected version 20261003000000, got: %d", version)
+	}
+
+	m, err = migrator.New(db, sparseLateFS, migrator.WithSparseVersions())
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+
+	var orderErr migrator.OutOfOrderMigrationError
+	if !errors.As(err, &orderErr) ||
+		orderErr.Version != 20261002000000 ||
+		orderErr.LatestVersion != 20261003000000 {
+		t.Fatalf("expected OutOfOrderMigrationError, got: %v", err)
+	}
+
+	_, err = db.Exec(`SELECT id FROM b`)
+	if err == nil {
+		t.Fatalf("expected table b to not exist, but it does")
+	}
+}
+
+func TestMigrate_OutOfOrder(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(db, sparseFS, migrator.WithSparseVersions())
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	m, err = migrator.New(
+		db, sparseLateFS, migrator.WithSparseVersions(), migrator.WithOutOfOrder(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	plan, err := m.Plan()
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+
+	if len(plan) != 1 || plan[0].Version != 20261002000000 {
+		t.Fatalf("expected only migration 20261002000000 to be pending, got: %+v", plan)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	_, err = db.Exec(`SELECT id FROM b`)
+	if err != nil {
+		t.Fatalf("expected table b to exist, got error: %v", err)
+	}
+
+	// the latest version is rolled back, not the latest applied one
+	err = m.Rollback(1)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 3 ||
+		!statuses[0].Applied ||
+		!statuses[1].Applied ||
+		statuses[2].Applied {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+
+	err = m.Rollback(3)
+
+	var invalidErr migrator.InvalidRollbackStepsError
+	if !errors.As(err, &invalidErr) || invalidErr.Applied != 2 {
+		t.Fatalf("expected InvalidRollbackStepsError with 2 applied migrations, got: %v", err)
+	}
+
+	err = m.Rollback(2)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 0 {
+		t.Fatalf("expected version 0, got: %d", version)
+	}
+}
@@ -0,0 +1,320 @@
+package migrator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// splitStatements splits SQL text into statements.
+//
+// Statements end with a semicolon, except when it is inside:
+//   - a single-quoted string, a double-quoted or backquoted identifier
+//   - an escape string like E'it\'s', where a backslash escapes the next character
+//   - a "--" or "/* */" comment
+//   - a dollar-quoted string, like PostgreSQL function bodies
+//   - a BEGIN ... END block of a CREATE TRIGGER, PROCEDURE, FUNCTION or EVENT statement, or a
+//     BEGIN ATOMIC ... END block
+//
+// Backslashes in other strings are not escapes, like in standard SQL. Statements using them to
+// escape quotes, like MySQL allows, must be written between StatementBegin and StatementEnd
+// markers.
+//
+// Returned statements are trimmed and keep their ending semicolon. Parts containing only
+// comments are dropped.
+func splitStatements(sql string) []string {
+	s := splitter{sql: sql}
+	return s.split()
+}
+
+type splitter struct {
+	sql string
+	pos int
+
+	statements []string
+	// start is the position of the current statement.
+	start int
+	// hasCode tells if the current statement contains anything else than comments.
+	hasCode bool
+	// firstWord is the first word of the current statement.
+	firstWord string
+	// object is the kind of object created by a CREATE statement, like TABLE or TRIGGER.
+	object string
+	// previous is the previous token of the current statement, an upper case word or a
+	// character.
+	previous string
+	// parens is the number of parentheses the current position is in.
+	parens int
+	// depth is the number of BEGIN ... END blocks the current statement is in.
+	depth int
+}
+
+// createdObjects are the kinds of objects of CREATE statements. Only the bodies of triggers
+// and routines can contain BEGIN ... END blocks.
+var createdObjects = map[string]bool{
+	"TABLE":     false,
+	"VIEW":      false,
+	"INDEX":     false,
+	"SEQUENCE":  false,
+	"TYPE":      false,
+	"SCHEMA":    false,
+	"DATABASE":  false,
+	"TRIGGER":   true,
+	"PROCEDURE": true,
+	"FUNCTION":  true,
+	"EVENT":     true,
+}
+
+func (s *splitter) split() []string {
+	for s.pos < len(s.sql) {
+		c := s.sql[s.pos]
+
+		switch {
+		case c == '-' && s.peek(1) == '-':
+			s.skipLineComment()
+		case c == '/' && s.peek(1) == '*':
+			s.skipBlockComment()
+		case unicode.IsSpace(rune(c)):
+			s.pos++
+		case c == '\'' || c == '"' || c == '`':
+			s.hasCode = true
+			s.skipQuoted(c)
+			s.previous = string(c)
+		case (c == 'E' || c == 'e') && s.peek(1) == '\'':
+			s.hasCode = true
+			s.pos++
+			s.skipEscapeString()
+			s.previous = "'"
+		case c == '$' && s.dollarTag() != "":
+			s.hasCode = true
+			s.skipDollarQuoted(s.dollarTag())
+			s.previous = "$"
+		case isWordStart(c):
+			s.hasCode = true
+			s.word()
+		case c == ';' && s.depth == 0:
+			s.pos++
+			s.endStatement()
+		default:
+			s.hasCode = true
+			s.punctuation(c)
+			s.pos++
+		}
+	}
+
+	s.endStatement()
+	return s.statements
+}
+
+func (s *splitter) peek(offset int) byte {
+	if s.pos+offset >= len(s.sql) {
+		return 0
+	}
+
+	return s.sql[s.pos+offset]
+}
+
+func (s *splitter) endStatement() {
+	if s.hasCode {
+		s.statements = append(s.statements, strings.TrimSpace(s.sql[s.start:s.pos]))
+	}
+
+	s.start = s.pos
+	s.hasCode = false
+	s.firstWord = ""
+	s.object = ""
+	s.previous = ""
+	s.parens = 0
+	s.depth = 0
+}
+
+func (s *splitter) skipLineComment() {
+	end := strings.IndexByte(s.sql[s.pos:], '\n')
+	if end == -1 {
+		s.pos = len(s.sql)
+	} else {
+		s.pos += end + 1
+	}
+}
+
+func (s *splitter) skipBlockComment() {
+	// block comments can be nested in PostgreSQL
+	depth := 0
+	for s.pos < len(s.sql) {
+		switch {
+		case s.sql[s.pos] == '/' && s.peek(1) == '*':
+			depth++
+			s.pos += 2
+		case s.sql[s.pos] == '*' && s.peek(1) == '/':
+			depth--
+			s.pos += 2
+			if depth == 0 {
+				return
+			}
+		default:
+			s.pos++
+		}
+	}
+}
+
+// skipQuoted skips a quoted string or identifier. A doubled quote is an escaped quote, it
+// is handled as two consecutive quoted parts.
+func (s *splitter) skipQuoted(quote byte) {
+	end := strings.IndexByte(s.sql[s.pos+1:], quote)
+	if end == -1 {
+		s.pos = len(s.sql)
+	} else {
+		s.pos += end + 2
+	}
+}
+
+// skipEscapeString skips a PostgreSQL escape string, after its E prefix. A backslash escapes
+// the next character, and a doubled quote is an escaped quote.
+func (s *splitter) skipEscapeString() {
+	for s.pos++; s.pos < len(s.sql); s.pos++ {
+		switch s.sql[s.pos] {
+		case '\\':
+			s.pos++
+		case '\'':
+			if s.peek(1) != '\'' {
+				s.pos++
+				return
+			}
+
+			s.pos++
+		}
+	}
+}
+
+// punctuation tracks the parentheses and the previous token of the current statement.
+func (s *splitter) punctuation(c byte) {
+	switch c {
+	case '(':
+		s.parens++
+	case ')':
+		s.parens = max(s.parens-1, 0)
+	}
+
+	s.previous = string(c)
+}
+
+// dollarTag returns the dollar quote tag at the current position, like "$$" or "$body$", or
+// an empty string if there is none.
+func (s *splitter) dollarTag() string {
+	for i := s.pos + 1; i < len(s.sql); i++ {
+		c := s.sql[i]
+		switch {
+		case c == '$':
+			return s.sql[s.pos : i+1]
+		case isWordStart(c) || (i > s.pos+1 && c >= '0' && c <= '9'):
+			continue
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func (s *splitter) skipDollarQuoted(tag string) {
+	end := strings.Index(s.sql[s.pos+len(tag):], tag)
+	if end == -1 {
+		s.pos = len(s.sql)
+	} else {
+		s.pos += len(tag) + end + len(tag)
+	}
+}
+
+// word reads a word and tracks BEGIN ... END blocks in CREATE statements.
+func (s *splitter) word() {
+	word := strings.ToUpper(s.readWord())
+	previous := s.previous
+	s.previous = word
+
+	if s.firstWord == "" {
+		s.firstWord = word
+	}
+
+	if s.firstWord != "CREATE" {
+		return
+	}
+
+	if _, ok := createdObjects[word]; ok && s.object == "" {
+		s.object = word
+	}
+
+	switch word {
+	case "BEGIN":
+		if s.opensBlock(previous) {
+			s.depth++
+		}
+	case "CASE":
+		s.depth++
+	case "END":
+		// END IF, END LOOP, etc. close blocks that were not counted, END CASE closes a CASE
+		pos := s.pos
+		s.skipSpaces()
+
+		switch next := strings.ToUpper(s.readWord()); next {
+		case "IF", "LOOP", "WHILE", "REPEAT":
+		case "CASE":
+			s.depth--
+		default:
+			s.pos = pos
+			s.depth--
+		}
+
+		s.depth = max(s.depth, 0)
+	}
+}
+
+// opensBlock tells if the BEGIN word just read opens a block, and is not a transaction
+// statement or an identifier like a column named "begin".
+func (s *splitter) opensBlock(previous string) bool {
+	pos := s.pos
+	defer func() { s.pos = pos }()
+
+	s.skipSpaces()
+	next := s.peek(0)
+	if strings.ToUpper(s.readWord()) == "ATOMIC" {
+		return true
+	}
+
+	if !createdObjects[s.object] || s.parens > 0 {
+		return false
+	}
+
+	switch previous {
+	case ",", ".", "OF", "SET":
+		return false
+	}
+
+	switch next {
+	case '=', ',', '.', ')':
+		return false
+	}
+
+	return true
+}
+
+func (s *splitter) readWord() string {
+	start := s.pos
+	for s.pos < len(s.sql) && isWordPart(s.sql[s.pos]) {
+		s.pos++
+	}
+
+	return s.sql[start:s.pos]
+}
+
+func (s *splitter) skipSpaces() {
+	for s.pos < len(s.sql) && unicode.IsSpace(rune(s.sql[s.pos])) {
+		s.pos++
+	}
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
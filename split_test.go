@@ -0,0 +1,274 @@
+package migrator_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+// executedStatements applies a migration on a fake database and returns the statements it
+// executed, excluding the ones on the history table.
+func executedStatements(t *testing.T, migration string) []string {
+	t.Helper()
+
+	db, recorder := newFakeDB()
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"1_test.sql": &fstest.MapFile{Data: []byte("-- +migrate Up\n" + migration)},
+	}
+
+	m, err := migrator.New(db, fsys)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	return slices.DeleteFunc(
+		recorder.Statements(),
+		func(stmt string) bool {
+			return strings.Contains(stmt, "schema_migrations") ||
+				strings.Contains(stmt, "pragma_table_list")
+		},
+	)
+}
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		sql      string
+		expected []string
+	}{
+		{
+			name:     "one statement",
+			sql:      "CREATE TABLE t (id INTEGER);\n",
+			expected: []string{"CREATE TABLE t (id INTEGER);"},
+		},
+		{
+			name: "multi-line statements",
+			sql:  "CREATE TABLE t (\n    id INTEGER\n);\nDROP TABLE u;\n",
+			expected: []string{
+				"CREATE TABLE t (\n    id INTEGER\n);",
+				"DROP TABLE u;",
+			},
+		},
+		{
+			name:     "statements on the same line",
+			sql:      "DELETE FROM t; DELETE FROM u;",
+			expected: []string{"DELETE FROM t;", "DELETE FROM u;"},
+		},
+		{
+			name:     "missing final semicolon",
+			sql:      "DELETE FROM t;\nDELETE FROM u\n",
+			expected: []string{"DELETE FROM t;", "DELETE FROM u"},
+		},
+		{
+			name:     "semicolon in a string",
+			sql:      "INSERT INTO t VALUES ('a;\nb');\nDELETE FROM t;",
+			expected: []string{"INSERT INTO t VALUES ('a;\nb');", "DELETE FROM t;"},
+		},
+		{
+			name:     "escaped quote in a string",
+			sql:      "INSERT INTO t VALUES ('it''s;');\nDELETE FROM t;",
+			expected: []string{"INSERT INTO t VALUES ('it''s;');", "DELETE FROM t;"},
+		},
+		{
+			name:     "backslash escape in an escape string",
+			sql:      "INSERT INTO t VALUES (E'it\\'s; x', e'\\\\');\nDELETE FROM t;",
+			expected: []string{"INSERT INTO t VALUES (E'it\\'s; x', e'\\\\');", "DELETE FROM t;"},
+		},
+		{
+			name:     "semicolon in quoted identifiers",
+			sql:      "SELECT \"a;b\", `c;d` FROM t;",
+			expected: []string{"SELECT \"a;b\", `c;d` FROM t;"},
+		},
+		{
+			name:     "semicolon in a line comment",
+			sql:      "-- first; statement\nDELETE FROM t;",
+			expected: []string{"-- first; statement\nDELETE FROM t;"},
+		},
+		{
+			name:     "semicolon in a block comment",
+			sql:      "DELETE /* ; /* nested; */ ; */ FROM t;",
+			expected: []string{"DELETE /* ; /* nested; */ ; */ FROM t;"},
+		},
+		{
+			name:     "trailing comment after the semicolon",
+			sql:      "DELETE FROM t; -- clean t\nDELETE FROM u; /* clean u */\n-- done\n",
+			expected: []string{"DELETE FROM t;", "-- clean t\nDELETE FROM u;"},
+		},
+		{
+			name: "dollar-quoted function body",
+			sql: "CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n    NEW.a := 1;\n" +
+				"    RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\nSELECT 1;",
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n    NEW.a := 1;\n" +
+					"    RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;",
+				"SELECT 1;",
+			},
+		},
+		{
+			name: "tagged dollar quote",
+			sql:  "DO $body$ BEGIN PERFORM '$$;'; END $body$;\nSELECT $1;",
+			expected: []string{
+				"DO $body$ BEGIN PERFORM '$$;'; END $body$;",
+				"SELECT $1;",
+			},
+		},
+		{
+			name: "trigger with BEGIN ... END",
+			sql: "CREATE TRIGGER tr AFTER INSERT ON t\nBEGIN\n" +
+				"    UPDATE t SET a = CASE WHEN a > 0 THEN 1 ELSE 0 END;\n" +
+				"    DELETE FROM u;\nEND;\nDELETE FROM t;",
+			expected: []string{
+				"CREATE TRIGGER tr AFTER INSERT ON t\nBEGIN\n" +
+					"    UPDATE t SET a = CASE WHEN a > 0 THEN 1 ELSE 0 END;\n" +
+					"    DELETE FROM u;\nEND;",
+				"DELETE FROM t;",
+			},
+		},
+		{
+			name: "procedure with END IF",
+			sql: "CREATE PROCEDURE p() BEGIN\n    IF 1 THEN\n        SELECT 1;\n" +
+				"    END IF;\nEND;\nSELECT 2;",
+			expected: []string{
+				"CREATE PROCEDURE p() BEGIN\n    IF 1 THEN\n        SELECT 1;\n" +
+					"    END IF;\nEND;",
+				"SELECT 2;",
+			},
+		},
+		{
+			name: "column named begin",
+			sql: "CREATE TABLE t (begin INTEGER, end INTEGER);\nINSERT INTO t VALUES (1, 2);\n" +
+				"INSERT INTO t VALUES (3, 4);",
+			expected: []string{
+				"CREATE TABLE t (begin INTEGER, end INTEGER);",
+				"INSERT INTO t VALUES (1, 2);",
+				"INSERT INTO t VALUES (3, 4);",
+			},
+		},
+		{
+			name: "trigger on a column named begin",
+			sql: "CREATE TRIGGER tr AFTER UPDATE OF begin ON t FOR EACH ROW\nBEGIN\n" +
+				"    UPDATE u SET begin = NEW.begin;\nEND;\nSELECT 1;",
+			expected: []string{
+				"CREATE TRIGGER tr AFTER UPDATE OF begin ON t FOR EACH ROW\nBEGIN\n" +
+					"    UPDATE u SET begin = NEW.begin;\nEND;",
+				"SELECT 1;",
+			},
+		},
+		{
+			name: "BEGIN ATOMIC function body",
+			sql: "CREATE FUNCTION f() RETURNS integer LANGUAGE SQL\nBEGIN ATOMIC\n" +
+				"    SELECT 1;\nEND;\nSELECT 2;",
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS integer LANGUAGE SQL\nBEGIN ATOMIC\n    SELECT 1;\nEND;",
+				"SELECT 2;",
+			},
+		},
+		{
+			name:     "transaction statements are not blocks",
+			sql:      "BEGIN;\nDELETE FROM t;\nEND;",
+			expected: []string{"BEGIN;", "DELETE FROM t;", "END;"},
+		},
+		{
+			name: "explicit statement",
+			sql: "DELETE FROM t;\n-- +migrate StatementBegin\nCREATE PROCEDURE p()\n" +
+				"BEGIN\n    IF 1 THEN SELECT 1; END IF; SELECT 'END;\n" +
+				"-- +migrate StatementEnd\nDELETE FROM u;\n",
+			expected: []string{
+				"DELETE FROM t;",
+				"CREATE PROCEDURE p()\nBEGIN\n    IF 1 THEN SELECT 1; END IF; SELECT 'END;",
+				"DELETE FROM u;",
+			},
+		},
+		{
+			name:     "only comments",
+			sql:      "-- nothing to do\n/* really */\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			statements := executedStatements(t, tt.sql)
+			if !slices.Equal(statements, tt.expected) {
+				t.Fatalf("expected statements %q, got: %q", tt.expected, statements)
+			}
+		})
+	}
+}
+
+func TestNew_UnbalancedStatementMarker(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sql  string
+		line int
+	}{
+		{
+			name: "missing end",
+			sql:  "DELETE FROM t;\n-- +migrate StatementBegin\nDELETE FROM u;\n",
+			line: 3,
+		},
+		{
+			name: "missing end before down",
+			sql: "-- +migrate StatementBegin\nDELETE FROM u;\n" +
+				"-- +migrate Down\nDELETE FROM t;\n",
+			line: 2,
+		},
+		{
+			name: "missing begin",
+			sql:  "DELETE FROM t;\n-- +migrate StatementEnd\n",
+			line: 3,
+		},
+		{
+			name: "nested begin",
+			sql: "-- +migrate StatementBegin\nDELETE FROM u;\n" +
+				"-- +migrate StatementBegin\nDELETE FROM t;\n-- +migrate StatementEnd\n",
+			line: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db := getDB(t)
+			defer db.Close()
+
+			fsys := fstest.MapFS{
+				"1_test.sql": &fstest.MapFile{Data: []byte("-- +migrate Up\n" + tt.sql)},
+			}
+
+			_, err := migrator.New(db, fsys)
+
+			var markerErr migrator.UnbalancedStatementMarkerError
+			if !errors.As(err, &markerErr) {
+				t.Fatalf("expected UnbalancedStatementMarkerError, got: %v", err)
+			}
+
+			if markerErr.Filename != "1_test.sql" || markerErr.Line != tt.line {
+				t.Fatalf(
+					"expected error in 1_test.sql at line %d, got: %s at line %d",
+					tt.line,
+					markerErr.Filename,
+					markerErr.Line,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package migrator_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+var unsquashedFS = fstest.MapFS{
+	"1_a.sql": createTable("a"),
+	"2_b.sql": createTable("b"),
+	"3_c.sql": createTable("c"),
+}
+
+// squashedFS is unsquashedFS with the migrations 1 to 3 squashed, and a new migration.
+var squashedFS = fstest.MapFS{
+	"3_squashed.sql": &fstest.MapFile{
+		Data: []byte(`-- +migrate Up
+-- +migrate Squash
+CREATE TABLE a (id INTEGER PRIMARY KEY);
+CREATE TABLE b (id INTEGER PRIMARY KEY);
+CREATE TABLE c (id INTEGER PRIMARY KEY);
+`),
+	},
+	"4_d.sql": &fstest.MapFile{
+		Data: []byte(
+			"-- +migrate Up\nCREATE TABLE d (id INTEGER PRIMARY KEY);\n" +
+				"-- +migrate Down\nDROP TABLE d;\n",
+		),
+	},
+}
+
+func TestMigrate_Squash(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, squashedFS)
+	defer db.Close()
+
+	err := m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	for _, table := range []string{"a", "b", "c", "d"} {
+		_, err = db.Exec(`SELECT id FROM ` + table)
+		if err != nil {
+			t.Fatalf("expected table %s to exist, got error: %v", table, err)
+		}
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+
+	if count != 4 {
+		t.Fatalf("expected versions 1 to 4 to be recorded, got %d rows", count)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 2 || !statuses[0].Applied || !statuses[1].Applied {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+
+	err = m.Rollback(2)
+
+	var downErr migrator.MissingDownMigrationError
+	if !errors.As(err, &downErr) || downErr.Version != 3 {
+		t.Fatalf("expected MissingDownMigrationError for version 3, got: %v", err)
+	}
+}
+
+func TestMigrate_SquashAlreadyApplied(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, unsquashedFS)
+	defer db.Close()
+
+	err := m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	m = getMigrator(t, db, squashedFS)
+
+	plan, err := m.Plan()
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+
+	if len(plan) != 1 || plan[0].Version != 4 {
+		t.Fatalf("expected only migration 4 to be pending, got: %+v", plan)
+	}
+
+	// the checksum of the squash migration differs from the one of 3_c.sql
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+}
+
+func TestMigrate_SquashPartiallyApplied(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, unsquashedFS)
+	defer db.Close()
+
+	err := m.MigrateTo(1)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	m = getMigrator(t, db, squashedFS)
+
+	err = m.Migrate()
+
+	var squashErr migrator.SquashedMigrationError
+	if !errors.As(err, &squashErr) || squashErr.Version != 1 || squashErr.SquashVersion != 3 {
+		t.Fatalf("expected SquashedMigrationError, got: %v", err)
+	}
+}
+
+func TestMigrateTo_BelowSquash(t *testing.T) {
+	// the files of the squashed migrations are deleted, so an empty database cannot reach them
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, squashedFS)
+	defer db.Close()
+
+	err := m.MigrateTo(2)
+
+	var invalidErr migrator.InvalidTargetVersionError
+	if !errors.As(err, &invalidErr) || invalidErr.Version != 2 {
+		t.Fatalf("expected InvalidTargetVersionError for version 2, got: %v", err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("failed to get current version: %v", err)
+	}
+
+	if version != 0 {
+		t.Fatalf("expected version 0, got: %d", version)
+	}
+}
+
+func TestBaseline_Squash(t *testing.T) {
+	// the versions whose file was deleted are recorded too
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, squashedFS)
+	defer db.Close()
+
+	err := m.Baseline(4)
+	if err != nil {
+		t.Fatalf("failed to baseline: %v", err)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+
+	if count != 4 {
+		t.Fatalf("expected versions 1 to 4 to be recorded, got %d rows", count)
+	}
+}
@@ -0,0 +1,155 @@
+package migrator
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// MigrationStatus is the state of a migration in the database.
+type MigrationStatus struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+	// Source is the name of the source of the migration, given to WithFS. It is empty for the
+	// migrations of the fs.FS given to New and for Go migrations.
+	Source string `json:"source,omitempty"`
+	// Applied tells if the migration is applied.
+	Applied bool `json:"applied"`
+	// AppliedAt is when the migration was applied. It is zero if the migration is pending.
+	AppliedAt time.Time `json:"applied_at,omitzero"`
+	// Missing tells that the migration is recorded in the history table but is unknown, for
+	// example because its file was deleted.
+	Missing bool `json:"missing,omitempty"`
+}
+
+func (m *migrator) Status() ([]MigrationStatus, error) {
+	return m.StatusContext(context.Background())
+}
+
+func (m *migrator) StatusContext(ctx context.Context) ([]MigrationStatus, error) {
+	err := m.setupHistoryTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := m.getHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var currentVersion int64
+	if len(history) > 0 {
+		currentVersion = history[len(history)-1].version
+	}
+
+	// the files of the migrations replaced by a squash migration are not missing
+	var squashedUpTo int64
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		if migration.squash {
+			squashedUpTo = max(squashedUpTo, migration.version)
+		}
+
+		statuses = append(statuses, MigrationStatus{
+			Version: migration.version,
+			Name:    migration.name,
+			Source:  migration.source,
+			// with sparse versions, only the versions in the history table are applied
+			Applied: !m.sparse && migration.version <= currentVersion,
+		})
+	}
+
+	for _, row := range history {
+		idx := slices.IndexFunc(
+			statuses, func(s MigrationStatus) bool { return s.Version == row.version },
+		)
+		if idx == -1 && row.version < squashedUpTo {
+			continue
+		}
+
+		if idx == -1 {
+			statuses = append(statuses, MigrationStatus{
+				Version:   row.version,
+				Applied:   true,
+				AppliedAt: row.appliedAt.Time,
+				Missing:   true,
+			})
+			continue
+		}
+
+		statuses[idx].Applied = true
+		statuses[idx].AppliedAt = row.appliedAt.Time
+	}
+
+	slices.SortFunc(
+		statuses, func(a, b MigrationStatus) int { return cmp.Compare(a.Version, b.Version) },
+	)
+	return statuses, nil
+}
+
+// WriteStatusTable writes migration statuses as an aligned text table, to be read in a
+// terminal.
+//
+// A SOURCE column is added if some migrations come from a source given to WithFS.
+func WriteStatusTable(w io.Writer, statuses []MigrationStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	withSource := slices.ContainsFunc(
+		statuses, func(s MigrationStatus) bool { return s.Source != "" },
+	)
+
+	header := []string{"VERSION", "NAME", "STATUS", "APPLIED AT"}
+	if withSource {
+		header = slices.Insert(header, 2, "SOURCE")
+	}
+
+	_, err := fmt.Fprintln(tw, strings.Join(header, "\t"))
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Missing:
+			state = "missing"
+		case status.Applied:
+			state = "applied"
+		}
+
+		appliedAt := ""
+		if !status.AppliedAt.IsZero() {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+
+		row := []string{strconv.FormatInt(status.Version, 10), status.Name, state, appliedAt}
+		if withSource {
+			row = slices.Insert(row, 2, status.Source)
+		}
+
+		_, err = fmt.Fprintln(tw, strings.Join(row, "\t"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// WriteStatusJSON writes migration statuses as a JSON array, for example in the response of
+// an HTTP endpoint.
+func WriteStatusJSON(w io.Writer, statuses []MigrationStatus) error {
+	if statuses == nil {
+		statuses = []MigrationStatus{}
+	}
+
+	return json.NewEncoder(w).Encode(statuses)
+}
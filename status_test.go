@@ -0,0 +1,148 @@
+package migrator_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsOKFS)
+	defer db.Close()
+
+	err := m.MigrateTo(2)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	// a migration applied by a newer version of the application
+	_, err = db.Exec(`INSERT INTO schema_migrations (version) VALUES (7)`)
+	if err != nil {
+		t.Fatalf("failed to insert version: %v", err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	expected := []struct {
+		version int64
+		name    string
+		applied bool
+		missing bool
+	}{
+		{version: 1, name: "test_table", applied: true},
+		{version: 2, name: "change_table", applied: true},
+		{version: 3, name: "another_test_table", applied: true},
+		{version: 4, name: "two_queries", applied: true},
+		{version: 7, applied: true, missing: true},
+	}
+
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected %d statuses, got: %+v", len(expected), statuses)
+	}
+
+	for i, e := range expected {
+		s := statuses[i]
+		if s.Version != e.version || s.Name != e.name || s.Applied != e.applied ||
+			s.Missing != e.missing {
+			t.Errorf("unexpected status %d: %+v", i, s)
+		}
+	}
+
+	if statuses[0].AppliedAt.IsZero() || statuses[4].AppliedAt.IsZero() {
+		t.Errorf("expected applied_at to be set, got: %+v", statuses)
+	}
+}
+
+func TestStatus_Pending(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(t, migrationsOKFS)
+	defer db.Close()
+
+	err := m.MigrateTo(3)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 4 || !statuses[2].Applied || statuses[3].Applied ||
+		!statuses[3].AppliedAt.IsZero() {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+}
+
+func TestStatus_LegacyTable(t *testing.T) {
+	t.Parallel()
+
+	db, m := getDBAndMigrator(
+		t,
+		migrationsOKFS,
+		`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY)`,
+		`INSERT INTO schema_migrations (version) VALUES (1)`,
+	)
+	defer db.Close()
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	// the table is upgraded with an applied_at column set to the upgrade time
+	if len(statuses) != 4 || !statuses[0].Applied || statuses[0].AppliedAt.IsZero() {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+}
+
+func TestWriteStatus(t *testing.T) {
+	t.Parallel()
+
+	statuses := []migrator.MigrationStatus{
+		{
+			Version:   1,
+			Name:      "test_table",
+			Applied:   true,
+			AppliedAt: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+		},
+		{Version: 2, Name: "change_table"},
+		{Version: 3, Applied: true, Missing: true},
+	}
+
+	var buf bytes.Buffer
+	err := migrator.WriteStatusTable(&buf, statuses)
+	if err != nil {
+		t.Fatalf("failed to write table: %v", err)
+	}
+
+	expected := "VERSION  NAME          STATUS   APPLIED AT\n" +
+		"1        test_table    applied  2026-10-17T12:00:00Z\n" +
+		"2        change_table  pending  \n" +
+		"3                      missing  \n"
+	if buf.String() != expected {
+		t.Fatalf("expected table %q, got: %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	err = migrator.WriteStatusJSON(&buf, statuses)
+	if err != nil {
+		t.Fatalf("failed to write JSON: %v", err)
+	}
+
+	expected = `[{"version":1,"name":"test_table","applied":true,` +
+		`"applied_at":"2026-10-17T12:00:00Z"},` +
+		`{"version":2,"name":"change_table","applied":false},` +
+		`{"version":3,"name":"","applied":true,"missing":true}]` + "\n"
+	if buf.String() != expected {
+		t.Fatalf("expected JSON %q, got: %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,48 @@
+package migrator
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"text/template"
+)
+
+// templateSuffix is the suffix of migration files rendered as templates.
+const templateSuffix = ".tmpl"
+
+// renderTemplate renders a migration template with the given data.
+func renderTemplate(filename string, r io.Reader, data map[string]any) (io.Reader, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(filename).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, newTemplateError(filename, err)
+	}
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, data)
+	if err != nil {
+		return nil, newTemplateError(filename, err)
+	}
+
+	return &b, nil
+}
+
+// newTemplateError returns a TemplateError with the line read from a text/template error.
+//
+// Parse and execution errors are formatted as "template: name:line: ..." or
+// "template: name:line:column: ...", but do not expose the line otherwise.
+func newTemplateError(filename string, err error) TemplateError {
+	rgx := regexp.MustCompile(`^template: ` + regexp.QuoteMeta(filename) + `:(\d+)`)
+
+	line := 0
+	if submatches := rgx.FindStringSubmatch(err.Error()); submatches != nil {
+		line, _ = strconv.Atoi(submatches[1])
+	}
+
+	return TemplateError{Filename: filename, Line: line, Err: err}
+}
@@ -0,0 +1,142 @@
+package migrator_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+var templateFS = fstest.MapFS{
+	"1_users.sql": &fstest.MapFile{
+		Data: []byte("-- +migrate Up\nCREATE TABLE users (id INTEGER PRIMARY KEY);\n"),
+	},
+	"2_tenant.sql.tmpl": &fstest.MapFile{
+		Data: []byte(
+			"-- +migrate Up\n" +
+				"{{range .tenants}}CREATE TABLE {{.}}_orders (id INTEGER PRIMARY KEY);\n{{end}}" +
+				"-- +migrate Down\n" +
+				"{{range .tenants}}DROP TABLE {{.}}_orders;\n{{end}}",
+		),
+	},
+}
+
+func TestMigrate_Template(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(
+		db,
+		templateFS,
+		migrator.WithTemplateData(map[string]any{"tenants": []string{"acme", "globex"}}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	for _, table := range []string{"users", "acme_orders", "globex_orders"} {
+		_, err = db.Exec("SELECT id FROM " + table)
+		if err != nil {
+			t.Errorf("expected table %s to exist, got: %v", table, err)
+		}
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	if len(statuses) != 2 || statuses[1].Name != "tenant" || !statuses[1].Applied {
+		t.Fatalf("expected migration tenant to be applied, got: %+v", statuses)
+	}
+
+	err = m.Rollback(1)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+
+	_, err = db.Exec("SELECT id FROM acme_orders")
+	if err == nil {
+		t.Fatalf("expected table acme_orders to be dropped")
+	}
+}
+
+func TestMigrate_TemplateDataChanged(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(
+		db,
+		templateFS,
+		migrator.WithTemplateData(map[string]any{"tenants": []string{"acme"}}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	_, err = migrator.New(
+		db,
+		templateFS,
+		migrator.WithTemplateData(map[string]any{"tenants": []string{"acme", "globex"}}),
+	)
+
+	// the checksum is computed on the rendered statements
+	var checksumErr migrator.ChecksumMismatchError
+	if !errors.As(err, &checksumErr) || checksumErr.Version != 2 {
+		t.Fatalf("expected ChecksumMismatchError for version 2, got: %v", err)
+	}
+}
+
+func TestNew_TemplateErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		template string
+		line     int
+	}{
+		{
+			name:     "missing key",
+			template: "-- +migrate Up\nCREATE TABLE t (id INTEGER);\nCREATE TABLE {{.prefix}}t;\n",
+			line:     3,
+		},
+		{
+			name:     "parse error",
+			template: "-- +migrate Up\n{{end}}\nCREATE TABLE t (id INTEGER);\n",
+			line:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fsys := fstest.MapFS{"1_t.sql.tmpl": &fstest.MapFile{Data: []byte(tt.template)}}
+
+			var templateErr migrator.TemplateError
+			err := migrator.Validate(fsys)
+			if !errors.As(err, &templateErr) {
+				t.Fatalf("expected TemplateError, got: %v", err)
+			}
+
+			if templateErr.Filename != "1_t.sql.tmpl" || templateErr.Line != tt.line {
+				t.Fatalf("expected error in 1_t.sql.tmpl at line %d, got: %v", tt.line, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+-- +migrate Up
+CREATE TABLE test_table (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL
+);
@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE test_table ADD COLUMN description TEXT;
+
+-- +migrate Down
+ALTER TABLE test_table DROP COLUMN description;
+
+-- +migrate Down
+ALTER TABLE test_table DROP COLUMN description;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE test_table ADD COLUMN description TEXT;
+
+-- +migrate Down
+ALTER TABLE test_table DROP COLUMN description;
@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE TABLE another_test_table (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL
+);
+INSERT INTO another_test_table (id, name) VALUES (1, 'Test Name 1');
+
+-- +migrate Down
+DELETE FROM another_test_table;
+DROP TABLE another_test_table;
@@ -0,0 +1,8 @@
+-- +migrate Up
+-- +migrate NoTransaction
+INSERT INTO test_table (id, name) VALUES (1, 'Test Name 1');
+VACUUM;
+
+-- +migrate Down
+DELETE FROM test_table;
+VACUUM;
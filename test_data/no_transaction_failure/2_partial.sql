@@ -0,0 +1,4 @@
+-- +migrate Up
+-- +migrate NoTransaction
+INSERT INTO test_table (id, name) VALUES (1, 'Test Name 1');
+INSERT INTO does_not_exist (id) VALUES (1);
@@ -1,41 +1,37 @@
 package migrator
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 )
 
-func (m *migrator) Version() (int, error) {
-	return getCurrentDBVersion(m.db)
+func (m *migrator) Version() (int64, error) {
+	return m.VersionContext(context.Background())
 }
 
-func getCurrentDBVersion(db *sql.DB) (int, error) {
-	// check if the table exists
-	_, err := db.Exec(`SELECT 1 FROM schema_migrations`)
-	if err != nil {
-		log.Print("error checking schema_migrations table: ", err)
-		log.Print("assuming schema_migrations table does not exist, try to create it")
+func (m *migrator) VersionContext(ctx context.Context) (int64, error) {
+	return m.getCurrentDBVersion(ctx)
+}
 
-		_, err = db.Exec(
-			`
-			CREATE TABLE schema_migrations (
-				version INTEGER PRIMARY KEY,
-				applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-			)
-			`,
-		)
-		if err != nil {
-			log.Print("error creating schema_migrations table: ", err)
-			return 0, fmt.Errorf("failed to create schema_migrations table: %w", err)
-		}
+func (m *migrator) getCurrentDBVersion(ctx context.Context) (int64, error) {
+	err := m.setupHistoryTable(ctx)
+	if err != nil || m.historyMissing {
 		return 0, err
 	}
 
-	var version int
-	err = db.QueryRow(
-		`SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1`,
+	// MAX() is used instead of ORDER BY ... LIMIT 1 as the latter is not supported by
+	// every database.
+	var version sql.NullInt64
+	err = m.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT MAX(version) FROM %s WHERE namespace = %s",
+			m.table,
+			m.dialect.Placeholder(1),
+		),
+		m.namespace,
 	).Scan(&version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -45,5 +41,388 @@ func getCurrentDBVersion(db *sql.DB) (int, error) {
 		return 0, err
 	}
 
-	return version, nil
+	return version.Int64, nil
+}
+
+// execer is implemented by *sql.Tx and *sql.Conn.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// isApplied tells, within a transaction or a connection, if a version is already applied.
+//
+// As migrations are applied in order, a version is applied if it or a later version is
+// recorded in the history table. With sparse versions, the version itself must be recorded.
+func (m *migrator) isApplied(ctx context.Context, e execer, version int64) (bool, error) {
+	operator := ">="
+	if m.sparse {
+		operator = "="
+	}
+
+	var count int
+	err := e.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT COUNT(*) FROM %s WHERE namespace = %s AND version %s %s",
+			m.table,
+			m.dialect.Placeholder(1),
+			operator,
+			m.dialect.Placeholder(2),
+		),
+		m.namespace,
+		version,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// isEmpty tells, within a transaction or a connection, if no migration is recorded in the
+// history table.
+func (m *migrator) isEmpty(ctx context.Context, e execer) (bool, error) {
+	var count int
+	err := e.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT COUNT(*) FROM %s WHERE namespace = %s",
+			m.table,
+			m.dialect.Placeholder(1),
+		),
+		m.namespace,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count == 0, nil
+}
+
+// recordMigration inserts a migration in the history table.
+func (m *migrator) recordMigration(ctx context.Context, e execer, migration Migration) error {
+	_, err := e.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"INSERT INTO %s (namespace, version, checksum) VALUES (%s, %s, %s)",
+			m.table,
+			m.dialect.Placeholder(1),
+			m.dialect.Placeholder(2),
+			m.dialect.Placeholder(3),
+		),
+		m.namespace,
+		migration.version,
+		sql.NullString{String: migration.checksum, Valid: migration.checksum != ""},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.version, err)
+	}
+
+	return nil
+}
+
+// unrecordMigration deletes a version from the history table.
+func (m *migrator) unrecordMigration(ctx context.Context, e execer, version int64) error {
+	_, err := e.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"DELETE FROM %s WHERE namespace = %s AND version = %s",
+			m.table,
+			m.dialect.Placeholder(1),
+			m.dialect.Placeholder(2),
+		),
+		m.namespace,
+		version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to unrecord migration %d: %w", version, err)
+	}
+
+	return nil
+}
+
+// setupHistoryTable creates the history table if it does not exist yet, or upgrades the
+// tables created by older versions.
+//
+// The table is created or upgraded while holding the lock.
+//
+// In dry run mode the database is not changed: a missing table is handled as an empty one, and
+// a table needing an upgrade is an error.
+func (m *migrator) setupHistoryTable(ctx context.Context) error {
+	if m.historyReady {
+		return nil
+	}
+
+	m.historyMissing = false
+
+	ready, err := m.isHistoryTableReady(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ready {
+		m.historyReady = true
+		return nil
+	}
+
+	if m.dryRun {
+		exists, err := m.tableExists(ctx, m.tableName)
+		if err != nil {
+			return err
+		}
+
+		upgradeStarted, err := m.tableExists(ctx, m.upgradeTableName())
+		if err != nil {
+			return err
+		}
+
+		if exists || upgradeStarted {
+			return HistoryTableUpgradeError{Table: m.table}
+		}
+
+		m.logger.Info("dry run: history table does not exist", "table", m.table)
+		m.historyMissing = true
+		return ctx.Err()
+	}
+
+	err = m.lock(ctx, func() error { return m.prepareHistoryTable(ctx) })
+	m.historyReady = err == nil
+	return err
+}
+
+// isHistoryTableReady tells if the history table exists with the current layout, and no
+// upgrade was interrupted.
+func (m *migrator) isHistoryTableReady(ctx context.Context) (bool, error) {
+	_, err := m.db.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT namespace, version, checksum, applied_at FROM %s WHERE 1 = 0", m.table,
+		),
+	)
+	if err != nil {
+		return false, ctx.Err()
+	}
+
+	upgradeStarted, err := m.tableExists(ctx, m.upgradeTableName())
+	return !upgradeStarted, err
+}
+
+// upgradeTableName returns the name of the temporary table used to upgrade the history table.
+func (m *migrator) upgradeTableName() string {
+	return m.tableName + "_upgrade"
+}
+
+// upgradeTable returns the quoted name of the temporary table used to upgrade the history
+// table.
+func (m *migrator) upgradeTable() string {
+	return qualifiedTableName(m.dialect, m.schema, m.upgradeTableName())
+}
+
+// tableExists tells if a table exists in the schema of the history table. The catalog is
+// queried, so that the database does not log an error when the table does not exist.
+func (m *migrator) tableExists(ctx context.Context, name string) (bool, error) {
+	var count int
+	err := m.db.QueryRowContext(ctx, m.dialect.TableExistsSQL(), m.schema, name).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if table %s exists: %w", name, err)
+	}
+
+	return count > 0, nil
+}
+
+// prepareHistoryTable creates the history table, upgrades it, or resumes an interrupted
+// upgrade.
+func (m *migrator) prepareHistoryTable(ctx context.Context) error {
+	upgradeStarted, err := m.tableExists(ctx, m.upgradeTableName())
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.ExecContext(ctx, "SELECT 1 FROM "+m.table)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		m.logger.Info(
+			"assuming history table does not exist, try to create it",
+			"table", m.table,
+			"error", err,
+		)
+
+		err = m.createHistoryTable(ctx)
+		if err != nil || !upgradeStarted {
+			return err
+		}
+
+		// the upgrade was interrupted after dropping the table
+		return m.resumeUpgrade(ctx)
+	}
+
+	return m.upgradeHistoryTable(ctx, upgradeStarted)
+}
+
+func (m *migrator) createHistoryTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, m.dialect.CreateHistoryTableSQL(m.table))
+	if err != nil {
+		// another process may have created it concurrently
+		_, checkErr := m.db.ExecContext(ctx, "SELECT 1 FROM "+m.table)
+		if checkErr == nil {
+			return nil
+		}
+
+		m.logger.Error("failed to create history table", "table", m.table, "error", err)
+		return fmt.Errorf("failed to create history table %s: %w", m.table, err)
+	}
+
+	return nil
+}
+
+// upgradeHistoryTable adds the checksum column and the namespace key if they are missing.
+//
+// If a previous upgrade was interrupted, it is resumed or started again.
+func (m *migrator) upgradeHistoryTable(ctx context.Context, upgradeStarted bool) error {
+	checkQuery := fmt.Sprintf("SELECT checksum FROM %s WHERE 1 = 0", m.table)
+
+	_, err := m.db.ExecContext(ctx, checkQuery)
+	if err != nil {
+		m.logger.Info("adding checksum column to history table", "table", m.table)
+
+		_, err = m.db.ExecContext(
+			ctx, m.dialect.AddColumnSQL(m.table, "checksum", "VARCHAR(64)"),
+		)
+		if err != nil {
+			// another process may have added it concurrently
+			_, checkErr := m.db.ExecContext(ctx, checkQuery)
+			if checkErr != nil {
+				return fmt.Errorf(
+					"failed to add checksum column to history table %s: %w", m.table, err,
+				)
+			}
+		}
+	}
+
+	checkQuery = fmt.Sprintf("SELECT namespace FROM %s WHERE 1 = 0", m.table)
+
+	_, err = m.db.ExecContext(ctx, checkQuery)
+	if err == nil {
+		if upgradeStarted {
+			// the upgrade was interrupted after recreating the table
+			return m.resumeUpgrade(ctx)
+		}
+
+		return nil
+	}
+
+	if upgradeStarted {
+		// the upgrade was interrupted before dropping the table, which still has every row
+		m.logger.Info("restarting interrupted history table upgrade", "table", m.table)
+
+		_, err = m.db.ExecContext(ctx, "DROP TABLE "+m.upgradeTable())
+		if err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", m.upgradeTable(), err)
+		}
+	}
+
+	m.logger.Info("adding namespace to history table", "table", m.table)
+
+	err = m.addNamespace(ctx)
+	if err != nil {
+		// another process may have upgraded it concurrently
+		_, checkErr := m.db.ExecContext(ctx, checkQuery)
+		if checkErr == nil {
+			return nil
+		}
+
+		return fmt.Errorf("failed to add namespace to history table %s: %w", m.table, err)
+	}
+
+	return nil
+}
+
+// addNamespace recreates a history table without namespace with the current layout, as the
+// primary key cannot be changed in a portable way.
+//
+// The rows are copied to a temporary table, which is copied back once the table is recreated.
+// Some databases like MySQL commit DDL statements implicitly, so if the upgrade is interrupted
+// the temporary table is kept and the upgrade is resumed by prepareHistoryTable.
+//
+// Existing rows are assigned to the default namespace. If the table has no applied_at column,
+// the rows get the current time.
+func (m *migrator) addNamespace(ctx context.Context) error {
+	columns := "version, checksum"
+	_, err := m.db.ExecContext(ctx, fmt.Sprintf("SELECT applied_at FROM %s WHERE 1 = 0", m.table))
+	if err == nil {
+		columns += ", applied_at"
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	tmpTable := m.upgradeTable()
+	for _, query := range []string{
+		m.dialect.CreateHistoryTableSQL(tmpTable),
+		fmt.Sprintf(
+			"INSERT INTO %s (%s) SELECT %s FROM %s", tmpTable, columns, columns, m.table,
+		),
+		"DROP TABLE " + m.table,
+		m.dialect.CreateHistoryTableSQL(m.table),
+		fmt.Sprintf(
+			"INSERT INTO %s (%s) SELECT %s FROM %s",
+			m.table,
+			historyColumns,
+			historyColumns,
+			tmpTable,
+		),
+		"DROP TABLE " + tmpTable,
+	} {
+		_, err = tx.ExecContext(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// historyColumns are the columns of the history table.
+const historyColumns = "namespace, version, checksum, applied_at"
+
+// resumeUpgrade copies back the rows of an interrupted upgrade from the temporary table to
+// the recreated history table.
+//
+// The temporary table has every row of the old table, so the rows copied back before the
+// interruption are deleted first.
+func (m *migrator) resumeUpgrade(ctx context.Context) error {
+	m.logger.Info("resuming interrupted history table upgrade", "table", m.table)
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	tmpTable := m.upgradeTable()
+	for _, query := range []string{
+		fmt.Sprintf("DELETE FROM %s WHERE namespace = ''", m.table),
+		fmt.Sprintf(
+			"INSERT INTO %s (%s) SELECT %s FROM %s",
+			m.table,
+			historyColumns,
+			historyColumns,
+			tmpTable,
+		),
+		"DROP TABLE " + tmpTable,
+	} {
+		_, err = tx.ExecContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to resume upgrade of history table %s: %w", m.table, err)
+		}
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,98 @@
+package migrator_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erdnaxeli/migrator"
+)
+
+var coreFS = fstest.MapFS{
+	"1_users.sql":  createTable("users"),
+	"3_orders.sql": createTable("orders"),
+}
+
+var auditFS = fstest.MapFS{
+	"2_audit_log.sql": createTable("audit_log"),
+}
+
+func TestMigrate_MultipleSources(t *testing.T) {
+	t.Parallel()
+
+	db := getDB(t)
+	defer db.Close()
+
+	m, err := migrator.New(db, coreFS, migrator.WithFS("audit", auditFS))
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	err = m.Migrate()
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	expected := []string{"", "audit", ""}
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected %d migrations, got: %+v", len(expected), statuses)
+	}
+
+	for i, status := range statuses {
+		if status.Version != int64(i+1) || !status.Applied || status.Source != expected[i] {
+			t.Errorf("expected version %d from %q to be applied, got: %+v", i+1, expected[i], status)
+		}
+	}
+
+	var b bytes.Buffer
+	err = migrator.WriteStatusTable(&b, statuses)
+	if err != nil {
+		t.Fatalf("failed to write status: %v", err)
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	if !strings.HasPrefix(lines[0], "VERSION  NAME       SOURCE  STATUS") ||
+		!strings.HasPrefix(lines[2], "2        audit_log  audit   applied") {
+		t.Fatalf("expected a source column, got:\n%s", b.String())
+	}
+}
+
+func TestNew_DuplicatedVersionAcrossSources(t *testing.T) {
+	t.Parallel()
+
+	pluginFS := fstest.MapFS{"3_plugin.sql": createTable("plugin")}
+	err := migrator.Validate(
+		coreFS,
+		migrator.WithFS("audit", auditFS),
+		migrator.WithFS("plugin", pluginFS),
+	)
+
+	var dupErr migrator.DuplicateMigrationVersionError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected DuplicateMigrationVersionError, got: %v", err)
+	}
+
+	expected := [2]string{"3_orders.sql", "plugin:3_plugin.sql"}
+	if dupErr.Version != 3 || dupErr.Filenames != expected {
+		t.Fatalf("expected version 3 in %v, got: %v", expected, err)
+	}
+}
+
+func TestNew_InvalidSource(t *testing.T) {
+	t.Parallel()
+
+	pluginFS := fstest.MapFS{"plugin.sql": createTable("plugin")}
+	err := migrator.Validate(coreFS, migrator.WithFS("extras", pluginFS))
+
+	var invalidErr migrator.InvalidMigrationFilenameError
+	if !errors.As(err, &invalidErr) || !strings.Contains(err.Error(), "extras") {
+		t.Fatalf("expected InvalidMigrationFilenameError naming the source, got: %v", err)
+	}
+}